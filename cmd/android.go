@@ -0,0 +1,383 @@
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/spf13/cobra"
+
+	"github.com/AuruOS/abg/core"
+	"github.com/AuruOS/orchid/cmdr"
+)
+
+func NewAndroidCommand() *cmdr.Command {
+	// Root command
+	cmd := cmdr.NewCommand(
+		"android",
+		abg.Trans("android.description"),
+		abg.Trans("android.description"),
+		nil,
+	)
+
+	// New subcommand
+	newCmd := cmdr.NewCommand(
+		"new",
+		abg.Trans("android.new.description"),
+		abg.Trans("android.new.description"),
+		newAndroidSubSystem,
+	)
+	newCmd.WithStringFlag(
+		cmdr.NewStringFlag(
+			"name",
+			"n",
+			abg.Trans("android.new.options.name.description"),
+			"",
+		),
+	)
+
+	// Rm subcommand
+	rmCmd := cmdr.NewCommand(
+		"rm",
+		abg.Trans("android.rm.description"),
+		abg.Trans("android.rm.description"),
+		rmAndroidSubSystem,
+	)
+	rmCmd.WithStringFlag(
+		cmdr.NewStringFlag(
+			"name",
+			"n",
+			abg.Trans("android.rm.options.name.description"),
+			"",
+		),
+	)
+	rmCmd.WithBoolFlag(
+		cmdr.NewBoolFlag(
+			"force",
+			"f",
+			abg.Trans("android.rm.options.force.description"),
+			false,
+		),
+	)
+
+	// Install subcommand
+	installCmd := cmdr.NewCommand(
+		"install",
+		abg.Trans("android.install.description"),
+		abg.Trans("android.install.description"),
+		installAndroidApp,
+	)
+	installCmd.Args = cobra.MinimumNArgs(1)
+	installCmd.WithStringFlag(
+		cmdr.NewStringFlag(
+			"name",
+			"n",
+			abg.Trans("android.install.options.name.description"),
+			"",
+		),
+	)
+	installCmd.WithBoolFlag(
+		cmdr.NewBoolFlag(
+			"no-export",
+			"x",
+			abg.Trans("android.install.options.noExport.description"),
+			false,
+		),
+	)
+
+	// Remove subcommand
+	removeCmd := cmdr.NewCommand(
+		"remove",
+		abg.Trans("android.remove.description"),
+		abg.Trans("android.remove.description"),
+		removeAndroidApp,
+	)
+	removeCmd.Args = cobra.MinimumNArgs(1)
+	removeCmd.WithStringFlag(
+		cmdr.NewStringFlag(
+			"name",
+			"n",
+			abg.Trans("android.remove.options.name.description"),
+			"",
+		),
+	)
+
+	// List subcommand
+	listCmd := cmdr.NewCommand(
+		"list",
+		abg.Trans("android.list.description"),
+		abg.Trans("android.list.description"),
+		listAndroid,
+	)
+	listCmd.WithStringFlag(
+		cmdr.NewStringFlag(
+			"name",
+			"n",
+			abg.Trans("android.list.options.name.description"),
+			"",
+		),
+	)
+	listCmd.WithBoolFlag(
+		cmdr.NewBoolFlag(
+			"json",
+			"j",
+			abg.Trans("android.list.options.json.description"),
+			false,
+		),
+	)
+
+	// Run subcommand
+	runCmd := cmdr.NewCommand(
+		"run",
+		abg.Trans("android.run.description"),
+		abg.Trans("android.run.description"),
+		runAndroidApp,
+	)
+	runCmd.Args = cobra.ExactArgs(1)
+	runCmd.WithStringFlag(
+		cmdr.NewStringFlag(
+			"name",
+			"n",
+			abg.Trans("android.run.options.name.description"),
+			"",
+		),
+	)
+
+	// Add subcommands to android
+	cmd.AddCommand(newCmd)
+	cmd.AddCommand(rmCmd)
+	cmd.AddCommand(installCmd)
+	cmd.AddCommand(removeCmd)
+	cmd.AddCommand(listCmd)
+	cmd.AddCommand(runCmd)
+
+	return cmd
+}
+
+func newAndroidSubSystem(cmd *cobra.Command, args []string) error {
+	name, _ := cmd.Flags().GetString("name")
+
+	if name == "" {
+		cmdr.Info.Println(abg.Trans("android.new.info.askName"))
+		fmt.Scanln(&name)
+		if name == "" {
+			cmdr.Error.Println(abg.Trans("android.new.error.emptyName"))
+			return nil
+		}
+	}
+
+	if _, err := core.LoadSubSystem(name, false); err == nil {
+		cmdr.Error.Printfln(abg.Trans("android.new.error.alreadyExists"), name)
+		return nil
+	}
+	if _, err := core.LoadAndroidSubSystem(name, false); err == nil {
+		cmdr.Error.Printfln(abg.Trans("android.new.error.alreadyExists"), name)
+		return nil
+	}
+
+	android, err := core.NewAndroidSubSystem(name, false)
+	if err != nil {
+		return err
+	}
+
+	spinner, _ := cmdr.Spinner.Start(fmt.Sprintf(abg.Trans("android.new.info.creating"), name))
+	err = android.Create()
+	if err != nil {
+		spinner.Fail()
+		return err
+	}
+
+	spinner.UpdateText(fmt.Sprintf(abg.Trans("android.new.info.success"), name))
+	spinner.Success()
+
+	return nil
+}
+
+func rmAndroidSubSystem(cmd *cobra.Command, args []string) error {
+	name, _ := cmd.Flags().GetString("name")
+	forceFlag, _ := cmd.Flags().GetBool("force")
+
+	if name == "" {
+		cmdr.Error.Println(abg.Trans("android.rm.error.noName"))
+		return nil
+	}
+
+	if !forceFlag {
+		cmdr.Info.Printfln(abg.Trans("android.rm.info.askConfirmation")+` [y/N]`, name)
+		var confirmation string
+		fmt.Scanln(&confirmation)
+		if strings.ToLower(confirmation) != "y" {
+			cmdr.Info.Println(abg.Trans("abg.info.aborting"))
+			return nil
+		}
+	}
+
+	android, err := core.LoadAndroidSubSystem(name, false)
+	if err != nil {
+		return err
+	}
+
+	err = android.Delete()
+	if err != nil {
+		return err
+	}
+
+	cmdr.Success.Printfln(abg.Trans("android.rm.info.success"), name)
+
+	return nil
+}
+
+// loadAndroidFromFlag loads the Android subsystem named by the --name flag,
+// falling back to the only one available when the flag is omitted.
+func loadAndroidFromFlag(cmd *cobra.Command) (*core.AndroidSubSystem, error) {
+	name, _ := cmd.Flags().GetString("name")
+	if name != "" {
+		return core.LoadAndroidSubSystem(name, false)
+	}
+
+	androids, err := core.ListAndroidSubSystems(false)
+	if err != nil {
+		return nil, err
+	}
+
+	switch len(androids) {
+	case 0:
+		return nil, fmt.Errorf(abg.Trans("android.error.noSubsystems"))
+	case 1:
+		return androids[0], nil
+	default:
+		return nil, fmt.Errorf(abg.Trans("android.error.noName"))
+	}
+}
+
+func installAndroidApp(cmd *cobra.Command, args []string) error {
+	noExport, _ := cmd.Flags().GetBool("no-export")
+
+	android, err := loadAndroidFromFlag(cmd)
+	if err != nil {
+		return err
+	}
+
+	for _, apk := range args {
+		if _, err := os.Stat(apk); err != nil {
+			return fmt.Errorf(abg.Trans("android.install.error.apkNotFound"), apk)
+		}
+
+		pkg, err := android.Install(apk, !noExport)
+		if err != nil {
+			return fmt.Errorf(abg.Trans("android.install.error.installing"), apk, err)
+		}
+
+		cmdr.Success.Printfln(abg.Trans("android.install.info.success"), pkg, android.Name)
+	}
+
+	return nil
+}
+
+func removeAndroidApp(cmd *cobra.Command, args []string) error {
+	android, err := loadAndroidFromFlag(cmd)
+	if err != nil {
+		return err
+	}
+
+	for _, pkg := range args {
+		err := android.Remove(pkg)
+		if err != nil {
+			return fmt.Errorf(abg.Trans("android.remove.error.removing"), pkg, err)
+		}
+
+		cmdr.Success.Printfln(abg.Trans("android.remove.info.success"), pkg, android.Name)
+	}
+
+	return nil
+}
+
+func listAndroid(cmd *cobra.Command, args []string) error {
+	jsonFlag, _ := cmd.Flags().GetBool("json")
+	name, _ := cmd.Flags().GetString("name")
+
+	// Without a name, list the Android subsystems themselves
+	if name == "" {
+		androids, err := core.ListAndroidSubSystems(false)
+		if err != nil {
+			return err
+		}
+
+		if jsonFlag {
+			jsonAndroids, err := json.MarshalIndent(androids, "", "  ")
+			if err != nil {
+				return err
+			}
+
+			fmt.Println(string(jsonAndroids))
+			return nil
+		}
+
+		if len(androids) == 0 {
+			cmdr.Info.Println(abg.Trans("android.list.info.noSubsystems"))
+			return nil
+		}
+
+		cmdr.Info.Printfln(abg.Trans("android.list.info.foundSubsystems"), len(androids))
+
+		table := core.CreateApxTable(os.Stdout)
+		table.SetHeader([]string{abg.Trans("subsystems.labels.name"), abg.Trans("subsystems.labels.status")})
+		for _, android := range androids {
+			table.Append([]string{android.Name, android.Status})
+		}
+		table.Render()
+
+		return nil
+	}
+
+	android, err := core.LoadAndroidSubSystem(name, false)
+	if err != nil {
+		return err
+	}
+
+	apps, err := android.ListApps()
+	if err != nil {
+		return err
+	}
+
+	if jsonFlag {
+		jsonApps, err := json.MarshalIndent(apps, "", "  ")
+		if err != nil {
+			return err
+		}
+
+		fmt.Println(string(jsonApps))
+		return nil
+	}
+
+	if len(apps) == 0 {
+		cmdr.Info.Printfln(abg.Trans("android.list.info.noApps"), android.Name)
+		return nil
+	}
+
+	cmdr.Info.Printfln(abg.Trans("android.list.info.foundApps"), len(apps), android.Name)
+
+	table := core.CreateApxTable(os.Stdout)
+	table.SetHeader([]string{abg.Trans("android.labels.name"), abg.Trans("android.labels.package")})
+	for _, app := range apps {
+		table.Append([]string{app.Name, app.Package})
+	}
+	table.Render()
+
+	return nil
+}
+
+func runAndroidApp(cmd *cobra.Command, args []string) error {
+	android, err := loadAndroidFromFlag(cmd)
+	if err != nil {
+		return err
+	}
+
+	err = android.Run(args[0])
+	if err != nil {
+		return fmt.Errorf(abg.Trans("android.run.error.running"), args[0], err)
+	}
+
+	return nil
+}
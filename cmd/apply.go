@@ -0,0 +1,128 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/spf13/cobra"
+
+	"github.com/AuruOS/abg/core"
+	"github.com/AuruOS/orchid/cmdr"
+)
+
+func NewApplyCommand() *cmdr.Command {
+	cmd := cmdr.NewCommand(
+		"apply",
+		abg.Trans("apply.description"),
+		abg.Trans("apply.description"),
+		applyWorkspace,
+	)
+	cmd.WithStringFlag(
+		cmdr.NewStringFlag(
+			"file",
+			"f",
+			abg.Trans("apply.options.file.description"),
+			"",
+		),
+	)
+	cmd.WithBoolFlag(
+		cmdr.NewBoolFlag(
+			"prune",
+			"p",
+			abg.Trans("apply.options.prune.description"),
+			false,
+		),
+	)
+	cmd.WithBoolFlag(
+		cmdr.NewBoolFlag(
+			"yes",
+			"y",
+			abg.Trans("apply.options.yes.description"),
+			false,
+		),
+	)
+
+	return cmd
+}
+
+func applyWorkspace(cmd *cobra.Command, args []string) error {
+	file, _ := cmd.Flags().GetString("file")
+	prune, _ := cmd.Flags().GetBool("prune")
+	yes, _ := cmd.Flags().GetBool("yes")
+
+	if file == "" {
+		cmdr.Error.Println(abg.Trans("apply.error.noFile"))
+		return nil
+	}
+
+	workspace, err := core.LoadWorkspace(file)
+	if err != nil {
+		return err
+	}
+	if prune {
+		workspace.Prune = true
+	}
+
+	changes, err := workspace.Plan()
+	if err != nil {
+		return err
+	}
+
+	pending := 0
+	table := core.CreateApxTable(os.Stdout)
+	table.SetHeader([]string{abg.Trans("subsystems.labels.name"), "Stack", "Action", "Details"})
+	for _, change := range changes {
+		if change.Action != core.WorkspaceActionNone {
+			pending++
+		}
+		table.Append([]string{change.Name, change.Stack, change.Action, strings.Join(change.Details, "\n")})
+	}
+	table.Render()
+
+	if pending == 0 {
+		cmdr.Info.Println(abg.Trans("apply.info.upToDate"))
+		return nil
+	}
+
+	if !yes {
+		cmdr.Info.Printfln(abg.Trans("apply.info.askConfirmation")+` [y/N]`, pending)
+		var confirmation string
+		fmt.Scanln(&confirmation)
+		if strings.ToLower(confirmation) != "y" {
+			cmdr.Info.Println(abg.Trans("abg.info.aborting"))
+			return nil
+		}
+	}
+
+	failed := 0
+	for _, change := range changes {
+		switch change.Action {
+		case core.WorkspaceActionCreate:
+			cmdr.Info.Printfln(abg.Trans("apply.info.creating"), change.Name, change.Stack)
+		case core.WorkspaceActionDrift:
+			cmdr.Info.Printfln(abg.Trans("apply.info.recreating"), change.Name, change.Stack)
+		case core.WorkspaceActionUpdate:
+			cmdr.Info.Printfln(abg.Trans("apply.info.updating"), change.Name)
+		case core.WorkspaceActionRemove:
+			cmdr.Info.Printfln(abg.Trans("apply.info.removing"), change.Name)
+		default:
+			continue
+		}
+
+		err := change.Apply()
+		if err != nil {
+			cmdr.Error.Printfln(abg.Trans("apply.error.applying"), change.Name, err)
+			failed++
+			continue
+		}
+	}
+
+	if failed > 0 {
+		return fmt.Errorf(abg.Trans("apply.error.failed"), failed)
+	}
+
+	cmdr.Success.Println(abg.Trans("apply.info.success"))
+
+	return nil
+}
@@ -0,0 +1,182 @@
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/spf13/cobra"
+
+	"github.com/AuruOS/abg/core"
+	"github.com/AuruOS/orchid/cmdr"
+)
+
+func NewBuildCommand() *cmdr.Command {
+	// Root command
+	cmd := cmdr.NewCommand(
+		"build",
+		abg.Trans("build.description"),
+		abg.Trans("build.description"),
+		buildRecipe,
+	)
+	cmd.WithStringFlag(
+		cmdr.NewStringFlag(
+			"subsystem",
+			"s",
+			abg.Trans("build.options.subsystem.description"),
+			"",
+		),
+	)
+	cmd.WithStringFlag(
+		cmdr.NewStringFlag(
+			"recipe",
+			"r",
+			abg.Trans("build.options.recipe.description"),
+			"",
+		),
+	)
+	cmd.WithBoolFlag(
+		cmdr.NewBoolFlag(
+			"no-export",
+			"x",
+			abg.Trans("build.options.noExport.description"),
+			false,
+		),
+	)
+
+	// List subcommand
+	listCmd := cmdr.NewCommand(
+		"list",
+		abg.Trans("build.list.description"),
+		abg.Trans("build.list.description"),
+		listBuilds,
+	)
+	listCmd.WithStringFlag(
+		cmdr.NewStringFlag(
+			"subsystem",
+			"s",
+			abg.Trans("build.list.options.subsystem.description"),
+			"",
+		),
+	)
+	listCmd.WithBoolFlag(
+		cmdr.NewBoolFlag(
+			"json",
+			"j",
+			abg.Trans("build.list.options.json.description"),
+			false,
+		),
+	)
+
+	// Add subcommands to build
+	cmd.AddCommand(listCmd)
+
+	return cmd
+}
+
+// loadRecipeArg loads a recipe from a file when the argument points to one,
+// otherwise by name from the recipes paths.
+func loadRecipeArg(recipeArg string) (*core.Recipe, error) {
+	if _, err := os.Stat(recipeArg); err == nil {
+		return core.LoadRecipeFromPath(recipeArg)
+	}
+
+	return core.LoadRecipe(recipeArg)
+}
+
+func buildRecipe(cmd *cobra.Command, args []string) error {
+	subSystemName, _ := cmd.Flags().GetString("subsystem")
+	recipeArg, _ := cmd.Flags().GetString("recipe")
+	noExport, _ := cmd.Flags().GetBool("no-export")
+
+	if recipeArg == "" && len(args) > 0 {
+		recipeArg = args[0]
+	}
+
+	if recipeArg == "" {
+		cmdr.Error.Println(abg.Trans("build.error.noRecipe"))
+		return nil
+	}
+
+	if subSystemName == "" {
+		cmdr.Error.Println(abg.Trans("build.error.noSubsystem"))
+		return nil
+	}
+
+	recipe, err := loadRecipeArg(recipeArg)
+	if err != nil {
+		return fmt.Errorf(abg.Trans("build.error.cannotLoad"), recipeArg, err)
+	}
+
+	subSystem, err := core.LoadSubSystem(subSystemName, false)
+	if err != nil {
+		return err
+	}
+
+	cmdr.Info.Printfln(abg.Trans("build.info.building"), recipe.Name, subSystem.Name)
+	record, err := recipe.Run(subSystem, noExport)
+	if err != nil {
+		return fmt.Errorf(abg.Trans("build.error.building"), recipe.Name, err)
+	}
+
+	for _, binary := range record.Exported {
+		cmdr.Info.Printfln(abg.Trans("runtimeCommand.info.exportedBin"), binary)
+	}
+
+	cmdr.Success.Printfln(abg.Trans("build.info.success"), recipe.Name, subSystem.Name)
+
+	return nil
+}
+
+func listBuilds(cmd *cobra.Command, args []string) error {
+	subSystemName, _ := cmd.Flags().GetString("subsystem")
+	jsonFlag, _ := cmd.Flags().GetBool("json")
+
+	if subSystemName == "" {
+		cmdr.Error.Println(abg.Trans("build.error.noSubsystem"))
+		return nil
+	}
+
+	subSystem, err := core.LoadSubSystem(subSystemName, false)
+	if err != nil {
+		return err
+	}
+
+	records, err := core.ListBuildRecords(subSystem)
+	if err != nil {
+		return err
+	}
+
+	if jsonFlag {
+		jsonRecords, err := json.MarshalIndent(records, "", "  ")
+		if err != nil {
+			return err
+		}
+
+		fmt.Println(string(jsonRecords))
+		return nil
+	}
+
+	if len(records) == 0 {
+		cmdr.Info.Printfln(abg.Trans("build.list.info.noBuilds"), subSystem.Name)
+		return nil
+	}
+
+	cmdr.Info.Printfln(abg.Trans("build.list.info.foundBuilds"), len(records), subSystem.Name)
+
+	table := core.CreateApxTable(os.Stdout)
+	table.SetHeader([]string{"Recipe", "Version", "Prefix", "Binaries", "Built"})
+	for _, record := range records {
+		table.Append([]string{
+			record.Recipe,
+			record.Version,
+			record.Prefix,
+			strings.Join(record.Exported, ", "),
+			record.BuiltAt.Format("02 Jan 2006 15:04:05"),
+		})
+	}
+	table.Render()
+
+	return nil
+}
@@ -0,0 +1,112 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/spf13/cobra"
+
+	"github.com/AuruOS/abg/core"
+	"github.com/AuruOS/orchid/cmdr"
+)
+
+// NewMaintenanceCommands returns the update and upgrade commands acting on
+// every subsystem at once.
+func NewMaintenanceCommands() []*cmdr.Command {
+	commands := make([]*cmdr.Command, 0)
+
+	for _, command := range []string{"update", "upgrade"} {
+		cmd := cmdr.NewCommand(
+			command,
+			abg.Trans("maintenance."+command+".description"),
+			abg.Trans("maintenance."+command+".description"),
+			runMaintenance(command),
+		)
+		cmd.WithBoolFlag(
+			cmdr.NewBoolFlag(
+				"all",
+				"a",
+				abg.Trans("maintenance.options.all.description"),
+				false,
+			),
+		)
+		cmd.WithIntFlag(
+			cmdr.NewIntFlag(
+				"jobs",
+				"j",
+				abg.Trans("maintenance.options.jobs.description"),
+				core.DefaultMaintenanceJobs,
+			),
+		)
+
+		commands = append(commands, cmd)
+	}
+
+	return commands
+}
+
+func runMaintenance(command string) func(cmd *cobra.Command, args []string) error {
+	return func(cmd *cobra.Command, args []string) error {
+		all, _ := cmd.Flags().GetBool("all")
+		jobs, _ := cmd.Flags().GetInt("jobs")
+
+		if !all {
+			cmdr.Error.Printfln(abg.Trans("maintenance.error.noAll"), command, command)
+			return nil
+		}
+
+		cmdr.Info.Printfln(abg.Trans("maintenance.info.running"), command)
+		results, err := core.RunMaintenance(command, jobs, func(result *core.MaintenanceResult) {
+			if result.Err != nil {
+				cmdr.Error.Printfln(abg.Trans("maintenance.error.subsystem"), result.SubSystem, result.Err)
+				return
+			}
+			cmdr.Info.Printfln(abg.Trans("maintenance.info.subsystemDone"), result.SubSystem)
+		})
+		if err != nil {
+			return err
+		}
+
+		if len(results) == 0 {
+			cmdr.Info.Println(abg.Trans("subsystems.list.info.noSubsystems"))
+			return nil
+		}
+
+		failed := 0
+		table := core.CreateApxTable(os.Stdout)
+		table.SetHeader([]string{abg.Trans("subsystems.labels.name"), "Stack", "Result", "Changed"})
+		for _, result := range results {
+			status := "ok"
+			if result.Err != nil {
+				status = "failed"
+				failed++
+			}
+
+			changed := "-"
+			if result.Changed >= 0 {
+				changed = fmt.Sprintf("%d", result.Changed)
+			}
+
+			table.Append([]string{result.SubSystem, result.Stack, status, changed})
+		}
+		table.Render()
+
+		for _, result := range results {
+			if result.StopErr != nil {
+				cmdr.Warning.Printfln(abg.Trans("maintenance.error.stopping"), result.SubSystem, result.StopErr)
+			}
+			if result.Err != nil && strings.TrimSpace(result.Output) != "" {
+				cmdr.Error.Printfln(abg.Trans("maintenance.error.output"), result.SubSystem)
+				fmt.Println(strings.TrimSpace(result.Output))
+			}
+		}
+
+		if failed > 0 {
+			return fmt.Errorf(abg.Trans("maintenance.error.failed"), failed, len(results))
+		}
+
+		cmdr.Success.Printfln(abg.Trans("maintenance.info.success"), len(results))
+		return nil
+	}
+}
@@ -271,6 +271,30 @@ func setupPkgManagerFlags(cmd *cmdr.Command) {
 			"",
 		),
 	)
+	cmd.WithStringFlag(
+		cmdr.NewStringFlag(
+			"add-repo",
+			"R",
+			abg.Trans("pkgmanagers.new.options.addRepo.description"),
+			"",
+		),
+	)
+	cmd.WithStringFlag(
+		cmdr.NewStringFlag(
+			"import-key",
+			"K",
+			abg.Trans("pkgmanagers.new.options.importKey.description"),
+			"",
+		),
+	)
+	cmd.WithStringFlag(
+		cmdr.NewStringFlag(
+			"assume-yes",
+			"Y",
+			abg.Trans("pkgmanagers.new.options.assumeYes.description"),
+			"",
+		),
+	)
 }
 
 func listPkgManagers(cmd *cobra.Command, args []string) error {
@@ -326,6 +350,10 @@ func showPkgManager(cmd *cobra.Command, args []string) error {
 	table.Append([]string{"Show", pkgManager.CmdShow})
 	table.Append([]string{"Update", pkgManager.CmdUpdate})
 	table.Append([]string{"Upgrade", pkgManager.CmdUpgrade})
+	table.Append([]string{"AddRepo", pkgManager.CmdAddRepo})
+	table.Append([]string{"ImportKey", pkgManager.CmdImportKey})
+	table.Append([]string{"AssumeYes", pkgManager.AssumeYes})
+	table.Append([]string{"Parsers", strings.Join(pkgManager.Parsers(), ", ")})
 	table.Render()
 
 	return nil
@@ -346,6 +374,9 @@ func newPkgManager(cmd *cobra.Command, args []string) error {
 		show, _       = cmd.Flags().GetString("show")
 		update, _     = cmd.Flags().GetString("update")
 		upgrade, _    = cmd.Flags().GetString("upgrade")
+		addRepo, _    = cmd.Flags().GetString("add-repo")
+		importKey, _  = cmd.Flags().GetString("import-key")
+		assumeYes, _  = cmd.Flags().GetString("assume-yes")
 	)
 
 	reader := bufio.NewReader(os.Stdin)
@@ -421,6 +452,9 @@ func newPkgManager(cmd *cobra.Command, args []string) error {
 		autoRemove, clean, install, list, purge, remove, search, show, update, upgrade,
 		false,
 	)
+	pkgManager.CmdAddRepo = addRepo
+	pkgManager.CmdImportKey = importKey
+	pkgManager.AssumeYes = assumeYes
 
 	if err := pkgManager.Save(); err != nil {
 		return fmt.Errorf("failed to save package manager: %w", err)
@@ -557,6 +591,9 @@ func updatePkgManager(cmd *cobra.Command, args []string) error {
 		show, _       = cmd.Flags().GetString("show")
 		update, _     = cmd.Flags().GetString("update")
 		upgrade, _    = cmd.Flags().GetString("upgrade")
+		addRepo, _    = cmd.Flags().GetString("add-repo")
+		importKey, _  = cmd.Flags().GetString("import-key")
+		assumeYes, _  = cmd.Flags().GetString("assume-yes")
 	)
 
 	if name == "" && (len(args) == 0 || args[0] == "") {
@@ -616,6 +653,18 @@ func updatePkgManager(cmd *cobra.Command, args []string) error {
 	pkgmanager.CmdUpdate = update
 	pkgmanager.CmdUpgrade = upgrade
 
+	// The repository commands and the assume-yes flag are optional, an
+	// explicitly empty value removes them
+	if cmd.Flags().Changed("add-repo") {
+		pkgmanager.CmdAddRepo = addRepo
+	}
+	if cmd.Flags().Changed("import-key") {
+		pkgmanager.CmdImportKey = importKey
+	}
+	if cmd.Flags().Changed("assume-yes") {
+		pkgmanager.AssumeYes = assumeYes
+	}
+
 	if err := pkgmanager.Save(); err != nil {
 		return fmt.Errorf("failed to save package manager: %w", err)
 	}
@@ -3,6 +3,9 @@ package cmd
 import (
 	"embed"
 
+	"github.com/spf13/cobra"
+
+	"github.com/AuruOS/abg/core"
 	"github.com/AuruOS/orchid/cmdr"
 )
 
@@ -10,7 +13,7 @@ var abg *cmdr.App
 
 func New(version string, fs embed.FS) *cmdr.App {
 	abg = cmdr.NewApp("abg", version, fs)
-	return apx
+	return abg
 }
 
 func NewRootCommand(version string) *cmdr.Command {
@@ -21,6 +24,18 @@ func NewRootCommand(version string) *cmdr.Command {
 		nil,
 	)
 	root.Version = version
+	root.WithPersistentBoolFlag(
+		cmdr.NewBoolFlag(
+			"dry-run",
+			"",
+			abg.Trans("abg.options.dryRun.description"),
+			false,
+		),
+	)
+	root.PersistentPreRun = func(cmd *cobra.Command, args []string) {
+		dryRun, _ := cmd.Flags().GetBool("dry-run")
+		core.SetDryRun(dryRun)
+	}
 
 	return root
 }
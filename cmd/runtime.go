@@ -1,6 +1,7 @@
 package cmd
 
 import (
+	"encoding/json"
 	"fmt"
 	"slices"
 
@@ -17,6 +18,18 @@ func NewRuntimeCommands() []*cmdr.Command {
 		return []*cmdr.Command{}
 	}
 
+	// Rootful subsystems come from their records, listing their containers
+	// would ask for authentication on every run
+	rootFullSubSystems, err := core.ListRecordedSubSystems(true)
+	if err != nil {
+		return []*cmdr.Command{}
+	}
+	for _, subSystem := range rootFullSubSystems {
+		if !slices.ContainsFunc(subSystems, func(s *core.SubSystem) bool { return s.Name == subSystem.Name }) && !subSystem.IsManaged {
+			subSystems = append(subSystems, subSystem)
+		}
+	}
+
 	handleFunc := func(subSystem *core.SubSystem, reqFunc func(*core.SubSystem, string, *cobra.Command, []string) error) func(cmd *cobra.Command, args []string) error {
 		return func(cmd *cobra.Command, args []string) error {
 			return reqFunc(subSystem, cmd.Name(), cmd, args)
@@ -65,6 +78,14 @@ func NewRuntimeCommands() []*cmdr.Command {
 			abg.Trans("runtimeCommand.list.description"),
 			handleFunc(subSystem, runPkgCmd),
 		)
+		listCmd.WithBoolFlag(
+			cmdr.NewBoolFlag(
+				"json",
+				"j",
+				abg.Trans("runtimeCommand.list.options.json.description"),
+				false,
+			),
+		)
 		purgeCmd := cmdr.NewCommand(
 			"purge",
 			abg.Trans("runtimeCommand.purge.description"),
@@ -83,12 +104,28 @@ func NewRuntimeCommands() []*cmdr.Command {
 			abg.Trans("runtimeCommand.search.description"),
 			handleFunc(subSystem, runPkgCmd),
 		)
+		searchCmd.WithBoolFlag(
+			cmdr.NewBoolFlag(
+				"json",
+				"j",
+				abg.Trans("runtimeCommand.search.options.json.description"),
+				false,
+			),
+		)
 		showCmd := cmdr.NewCommand(
 			"show",
 			abg.Trans("runtimeCommand.show.description"),
 			abg.Trans("runtimeCommand.show.description"),
 			handleFunc(subSystem, runPkgCmd),
 		)
+		showCmd.WithBoolFlag(
+			cmdr.NewBoolFlag(
+				"json",
+				"j",
+				abg.Trans("runtimeCommand.show.options.json.description"),
+				false,
+			),
+		)
 		updateCmd := cmdr.NewCommand(
 			"update",
 			abg.Trans("runtimeCommand.update.description"),
@@ -188,6 +225,44 @@ func NewRuntimeCommands() []*cmdr.Command {
 			handleFunc(subSystem, runPkgCmd),
 		)
 
+		lockCmd := cmdr.NewCommand(
+			"lock",
+			abg.Trans("runtimeCommand.lock.description"),
+			abg.Trans("runtimeCommand.lock.description"),
+			handleFunc(subSystem, runPkgCmd),
+		)
+		lockCmd.WithStringFlag(
+			cmdr.NewStringFlag(
+				"output",
+				"o",
+				abg.Trans("runtimeCommand.lock.options.output.description"),
+				"",
+			),
+		)
+
+		syncCmd := cmdr.NewCommand(
+			"sync",
+			abg.Trans("runtimeCommand.sync.description"),
+			abg.Trans("runtimeCommand.sync.description"),
+			handleFunc(subSystem, runPkgCmd),
+		)
+		syncCmd.WithBoolFlag(
+			cmdr.NewBoolFlag(
+				"locked",
+				"l",
+				abg.Trans("runtimeCommand.sync.options.locked.description"),
+				false,
+			),
+		)
+		syncCmd.WithStringFlag(
+			cmdr.NewStringFlag(
+				"file",
+				"f",
+				abg.Trans("runtimeCommand.sync.options.file.description"),
+				"",
+			),
+		)
+
 		subSystemCmd.AddCommand(autoRemoveCmd)
 		subSystemCmd.AddCommand(cleanCmd)
 		subSystemCmd.AddCommand(installCmd)
@@ -204,6 +279,8 @@ func NewRuntimeCommands() []*cmdr.Command {
 		subSystemCmd.AddCommand(unexportCmd)
 		subSystemCmd.AddCommand(startCmd)
 		subSystemCmd.AddCommand(stopCmd)
+		subSystemCmd.AddCommand(lockCmd)
+		subSystemCmd.AddCommand(syncCmd)
 
 		commands = append(commands, subSystemCmd)
 	}
@@ -211,7 +288,11 @@ func NewRuntimeCommands() []*cmdr.Command {
 	return commands
 }
 
-var baseCmds = []string{"run", "enter", "export", "unexport", "start", "stop"}
+var baseCmds = []string{"run", "enter", "export", "unexport", "start", "stop", "lock", "sync"}
+
+// lockingCmds are the package manager commands changing the installed
+// packages, after which the subsystem lock file is refreshed
+var lockingCmds = []string{"install", "remove", "purge", "autoremove", "upgrade"}
 
 // isBaseCommand informs whether the command is a subsystem-base command
 // (e.g. run, enter) instead of a subsystem-specific one (e.g. install, update)
@@ -267,6 +348,12 @@ func runPkgCmd(subSystem *core.SubSystem, command string, cmd *cobra.Command, ar
 		}
 
 		finalArgs := pkgManager.GenCmd(realCommand, args...)
+
+		jsonFlag, _ := cmd.Flags().GetBool("json")
+		if jsonFlag {
+			return printPackagesJSON(subSystem, pkgManager, command, finalArgs)
+		}
+
 		_, err = subSystem.Exec(false, false, finalArgs...)
 		if err != nil {
 			return fmt.Errorf(abg.Trans("runtimeCommand.error.executingCommand"), err)
@@ -279,6 +366,23 @@ func runPkgCmd(subSystem *core.SubSystem, command string, cmd *cobra.Command, ar
 			}
 		}
 
+		switch command {
+		case "install":
+			err = subSystem.TrackInstalled(args...)
+		case "remove", "purge":
+			err = subSystem.TrackRemoved(args...)
+		}
+		if err != nil {
+			cmdr.Warning.Printfln(abg.Trans("runtimeCommand.error.trackingPackages"), err)
+		}
+
+		if slices.Contains(lockingCmds, command) {
+			err := subSystem.UpdateLock()
+			if err != nil {
+				cmdr.Warning.Printfln(abg.Trans("runtimeCommand.error.updatingLock"), err)
+			}
+		}
+
 		return nil
 	}
 
@@ -300,6 +404,47 @@ func runPkgCmd(subSystem *core.SubSystem, command string, cmd *cobra.Command, ar
 		return nil
 	}
 
+	if command == "lock" {
+		output, _ := cmd.Flags().GetString("output")
+		if output == "" {
+			output = core.LockFilePath(subSystem)
+		}
+
+		lock, err := subSystem.Lock()
+		if err != nil {
+			return fmt.Errorf(abg.Trans("runtimeCommand.error.locking"), err)
+		}
+
+		err = lock.Save(output)
+		if err != nil {
+			return fmt.Errorf(abg.Trans("runtimeCommand.error.locking"), err)
+		}
+
+		cmdr.Info.Printfln(abg.Trans("runtimeCommand.info.locked"), len(lock.Packages), output)
+		return nil
+	}
+
+	if command == "sync" {
+		locked, _ := cmd.Flags().GetBool("locked")
+		file, _ := cmd.Flags().GetString("file")
+		if file == "" {
+			file = core.LockFilePath(subSystem)
+		}
+
+		lock, err := core.LoadLockFile(file)
+		if err != nil {
+			return fmt.Errorf(abg.Trans("runtimeCommand.error.syncing"), err)
+		}
+
+		installedN, err := subSystem.Sync(lock, locked)
+		if err != nil {
+			return fmt.Errorf(abg.Trans("runtimeCommand.error.syncing"), err)
+		}
+
+		cmdr.Info.Printfln(abg.Trans("runtimeCommand.info.synced"), installedN)
+		return nil
+	}
+
 	if command == "export" || command == "unexport" {
 		appName, _ := cmd.Flags().GetString("app-name")
 		bin, _ := cmd.Flags().GetString("bin")
@@ -331,6 +476,28 @@ func runPkgCmd(subSystem *core.SubSystem, command string, cmd *cobra.Command, ar
 	return nil
 }
 
+// printPackagesJSON runs a list, search or show command and prints its
+// output as JSON, parsed with the parser declared by the package manager.
+func printPackagesJSON(subSystem *core.SubSystem, pkgManager *core.PkgManager, command string, finalArgs []string) error {
+	out, err := subSystem.Query(finalArgs...)
+	if err != nil {
+		return fmt.Errorf(abg.Trans("runtimeCommand.error.executingCommand"), err)
+	}
+
+	packages, err := pkgManager.ParseOutput(command, out)
+	if err != nil {
+		return fmt.Errorf(abg.Trans("runtimeCommand.error.parsingOutput"), err)
+	}
+
+	jsonPackages, err := json.MarshalIndent(packages, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	fmt.Println(string(jsonPackages))
+	return nil
+}
+
 func handleExport(subSystem *core.SubSystem, command, appName, bin, binOutput string) error {
 	if appName == "" && bin == "" {
 		return fmt.Errorf(abg.Trans("runtimeCommand.error.noAppNameOrBin"))
@@ -0,0 +1,321 @@
+package cmd
+
+import (
+	"crypto/md5"
+	"embed"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/spf13/cobra"
+
+	"github.com/AuruOS/abg/core"
+	"github.com/AuruOS/abg/settings"
+	"github.com/AuruOS/orchid/cmdr"
+)
+
+// testEngine is a podman stand-in: a script logging its arguments, one
+// call per line, and printing the output set for them.
+const testEngine = `#!/bin/sh
+echo "${ABG_TEST_ROOTFUL:+root }$*" >> "$ABG_TEST_ENGINE/calls"
+if [ "$1" = ps ]; then
+	echo "[]"
+	exit 0
+fi
+out="$ABG_TEST_ENGINE/$(printf '%s' "$*" | md5sum | cut -d' ' -f1)"
+[ -f "$out" ] && cat "$out"
+exit 0
+`
+
+// engine reads what the test engine was called with.
+type engine struct {
+	dir string
+}
+
+// setOutput sets what the engine prints when called with the arguments,
+// joined by spaces.
+func (e *engine) setOutput(t *testing.T, args, output string) {
+	t.Helper()
+
+	writeFile(t, filepath.Join(e.dir, fmt.Sprintf("%x", md5.Sum([]byte(args)))), output, 0644)
+}
+
+// calls returns the engine calls, rootful ones prefixed by "root ".
+func (e *engine) calls(t *testing.T) []string {
+	t.Helper()
+
+	content, err := os.ReadFile(filepath.Join(e.dir, "calls"))
+	if err != nil {
+		if os.IsNotExist(err) {
+			return []string{}
+		}
+		t.Fatal(err)
+	}
+
+	return strings.Split(strings.TrimSuffix(string(content), "\n"), "\n")
+}
+
+// execCommands returns the commands run in the container through podman
+// exec, prefixed by sudo when run as root.
+func (e *engine) execCommands(t *testing.T, container string) [][]string {
+	t.Helper()
+
+	commands := make([][]string, 0)
+	for _, call := range e.calls(t) {
+		args := strings.Fields(strings.TrimPrefix(call, "root "))
+		i := slices.Index(args, container)
+		if len(args) == 0 || args[0] != "exec" || i < 0 {
+			continue
+		}
+
+		command := args[i+1:]
+		if slices.Contains(args[:i], "--user") {
+			command = append([]string{"sudo"}, command...)
+		}
+		commands = append(commands, command)
+	}
+
+	return commands
+}
+
+// setupRuntimeTest initializes abg on a temporary tree with the podman
+// backend running the test engine, and returns a subsystem using an
+// apt-like package manager.
+func setupRuntimeTest(t *testing.T) (*core.SubSystem, *engine) {
+	t.Helper()
+
+	dir := t.TempDir()
+	t.Setenv("HOME", dir)
+
+	// The essential checks look for distrobox and a container engine
+	binDir := filepath.Join(dir, "bin")
+	writeFile(t, filepath.Join(binDir, "distrobox"), "#!/bin/sh\n", 0755)
+	writeFile(t, filepath.Join(binDir, "podman"), testEngine, 0755)
+	writeFile(t, filepath.Join(binDir, "pkexec"), "#!/bin/sh\nABG_TEST_ROOTFUL=1 exec \"$@\"\n", 0755)
+	t.Setenv("PATH", binDir+string(os.PathListSeparator)+os.Getenv("PATH"))
+
+	e := &engine{dir: filepath.Join(dir, "engine")}
+	err := os.MkdirAll(e.dir, 0755)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv("ABG_TEST_ENGINE", e.dir)
+
+	cnf := settings.NewAbgConfig(filepath.Join(dir, "usr"), filepath.Join(binDir, "distrobox"), "overlay")
+	cnf.Backend = "podman"
+	if core.NewAbg(cnf) == nil {
+		t.Fatal("failed to initialize abg")
+	}
+
+	pkgManager := core.NewPkgManager("apt", true, "", "", "apt install -y", "apt list --installed", "", "apt remove -y", "apt search", "", "", "", false)
+	if err := pkgManager.Save(); err != nil {
+		t.Fatal(err)
+	}
+
+	if abg == nil {
+		abg = cmdr.NewApp("abg", "test", embed.FS{})
+	}
+
+	stack := core.NewStack("ubuntu", "docker.io/library/ubuntu:22.04", nil, "apt", false)
+	subSystem := &core.SubSystem{InternalName: "abg-dev", Name: "dev", Stack: stack}
+
+	return subSystem, e
+}
+
+func writeFile(t *testing.T, path, content string, perm os.FileMode) {
+	t.Helper()
+
+	err := os.MkdirAll(filepath.Dir(path), 0755)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = os.WriteFile(path, []byte(content), perm)
+	if err != nil {
+		t.Fatal(err)
+	}
+}
+
+// newRuntimeCmd returns a command with the flags runPkgCmd reads, set as
+// given.
+func newRuntimeCmd(t *testing.T, name string, flags ...string) *cobra.Command {
+	t.Helper()
+
+	cmd := &cobra.Command{Use: name}
+	cmd.Flags().Bool("no-export", false, "")
+	cmd.Flags().Bool("json", false, "")
+	for _, flag := range flags {
+		err := cmd.Flags().Set(flag, "true")
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	return cmd
+}
+
+func TestRunPkgCmdInstall(t *testing.T) {
+	subSystem, e := setupRuntimeTest(t)
+
+	err := runPkgCmd(subSystem, "install", newRuntimeCmd(t, "install", "no-export"), []string{"htop"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// The lock file is refreshed from the installed packages
+	want := [][]string{
+		{"sudo", "apt", "install", "-y", "htop"},
+		{"sudo", "apt", "list", "--installed"},
+	}
+	if got := e.execCommands(t, "abg-dev"); !slices.EqualFunc(got, want, slices.Equal[[]string]) {
+		t.Errorf("exec calls = %v, want %v", got, want)
+	}
+
+	tracking, err := subSystem.LoadTracking()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !slices.Contains(tracking.Installed, "htop") {
+		t.Errorf("htop not tracked: %+v", tracking)
+	}
+}
+
+func TestRunPkgCmdInstallExports(t *testing.T) {
+	subSystem, e := setupRuntimeTest(t)
+	e.setOutput(t, "exec --interactive abg-dev ls -1 /usr/share/applications", "htop.desktop\n")
+	e.setOutput(t, "exec --interactive abg-dev cat /usr/share/applications/htop.desktop", "[Desktop Entry]\nName=htop\nExec=htop\n")
+
+	err := runPkgCmd(subSystem, "install", newRuntimeCmd(t, "install"), []string{"htop"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	exports, err := subSystem.LoadExports()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(exports) != 1 || exports[0].Name != "htop" || len(exports[0].HostFiles) != 1 {
+		t.Fatalf("expected htop to be exported, got %+v", exports)
+	}
+
+	_, err = os.Stat(exports[0].HostFiles[0])
+	if err != nil {
+		t.Errorf("desktop entry not written: %v", err)
+	}
+}
+
+func TestRunPkgCmdRemove(t *testing.T) {
+	subSystem, e := setupRuntimeTest(t)
+	e.setOutput(t, "exec --interactive abg-dev ls -1 /usr/share/applications", "htop.desktop\n")
+
+	err := subSystem.ExportDesktopEntry("htop")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = runPkgCmd(subSystem, "remove", newRuntimeCmd(t, "remove"), []string{"htop"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Apps are unexported with the package
+	exports, err := subSystem.LoadExports()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(exports) != 0 {
+		t.Errorf("htop should be unexported, got %+v", exports)
+	}
+
+	commands := e.execCommands(t, "abg-dev")
+	if !slices.ContainsFunc(commands, func(c []string) bool { return slices.Equal(c, []string{"sudo", "apt", "remove", "-y", "htop"}) }) {
+		t.Errorf("htop not removed: %v", commands)
+	}
+}
+
+func TestRunPkgCmdRun(t *testing.T) {
+	subSystem, e := setupRuntimeTest(t)
+
+	err := runPkgCmd(subSystem, "run", newRuntimeCmd(t, "run"), []string{"ls", "-la"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := [][]string{{"ls", "-la"}}
+	if got := e.execCommands(t, "abg-dev"); !slices.EqualFunc(got, want, slices.Equal[[]string]) {
+		t.Errorf("exec calls = %v, want %v", got, want)
+	}
+}
+
+func TestRunPkgCmdSearchJSON(t *testing.T) {
+	subSystem, e := setupRuntimeTest(t)
+	e.setOutput(t, "exec --interactive --user root abg-dev apt search htop", "htop/jammy 3.0.5-7 amd64\n  interactive processes viewer\n")
+
+	err := runPkgCmd(subSystem, "search", newRuntimeCmd(t, "search", "json"), []string{"htop"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := [][]string{{"sudo", "apt", "search", "htop"}}
+	if got := e.execCommands(t, "abg-dev"); !slices.EqualFunc(got, want, slices.Equal[[]string]) {
+		t.Errorf("exec calls = %v, want %v", got, want)
+	}
+}
+
+func TestRunPkgCmdUnknown(t *testing.T) {
+	subSystem, e := setupRuntimeTest(t)
+
+	err := runPkgCmd(subSystem, "frobnicate", newRuntimeCmd(t, "frobnicate"), nil)
+	if err == nil {
+		t.Error("unknown commands should fail")
+	}
+	if calls := e.calls(t); len(calls) != 0 {
+		t.Errorf("nothing should run, got %v", calls)
+	}
+}
+
+func TestRuntimeCommandsIncludeRootful(t *testing.T) {
+	subSystem, e := setupRuntimeTest(t)
+
+	subSystem.IsRootfull = true
+	err := subSystem.Create()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	commands := NewRuntimeCommands()
+	if len(commands) != 1 || commands[0].Name() != "dev" {
+		t.Fatalf("rootful subsystem should have a runtime command, got %d commands", len(commands))
+	}
+	for _, call := range e.calls(t) {
+		if strings.HasPrefix(call, "root ps") {
+			t.Error("rootful containers should not be listed to build runtime commands")
+		}
+	}
+
+	recorded, err := core.ListRecordedSubSystems(true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(recorded) != 1 {
+		t.Fatalf("expected one recorded rootful subsystem, got %d", len(recorded))
+	}
+
+	err = runPkgCmd(recorded[0], "install", newRuntimeCmd(t, "install", "no-export"), []string{"htop"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	installed := false
+	for _, call := range e.calls(t) {
+		if strings.HasSuffix(call, "abg-dev apt install -y htop") {
+			installed = strings.HasPrefix(call, "root exec")
+		}
+	}
+	if !installed {
+		t.Errorf("commands should run in the rootful container: %v", e.calls(t))
+	}
+}
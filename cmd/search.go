@@ -0,0 +1,83 @@
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"sort"
+
+	"github.com/spf13/cobra"
+
+	"github.com/AuruOS/abg/core"
+	"github.com/AuruOS/orchid/cmdr"
+)
+
+func NewSearchCommand() *cmdr.Command {
+	cmd := cmdr.NewCommand(
+		"search",
+		abg.Trans("search.description"),
+		abg.Trans("search.description"),
+		searchPackages,
+	)
+	cmd.Args = cobra.ExactArgs(1)
+	cmd.WithBoolFlag(
+		cmdr.NewBoolFlag(
+			"json",
+			"j",
+			abg.Trans("search.options.json.description"),
+			false,
+		),
+	)
+
+	return cmd
+}
+
+func searchPackages(cmd *cobra.Command, args []string) error {
+	jsonFlag, _ := cmd.Flags().GetBool("json")
+
+	matches, failed, err := core.SearchPackages(args[0])
+	if err != nil {
+		return err
+	}
+
+	failedNames := make([]string, 0, len(failed))
+	for name := range failed {
+		failedNames = append(failedNames, name)
+	}
+	sort.Strings(failedNames)
+	for _, name := range failedNames {
+		cmdr.Warning.Printfln(abg.Trans("search.error.searching"), name, failed[name])
+	}
+
+	if jsonFlag {
+		jsonMatches, err := json.MarshalIndent(matches, "", "  ")
+		if err != nil {
+			return err
+		}
+
+		fmt.Println(string(jsonMatches))
+		return nil
+	}
+
+	if len(matches) == 0 {
+		cmdr.Info.Printfln(abg.Trans("search.info.noMatches"), args[0])
+		return nil
+	}
+
+	table := core.CreateApxTable(os.Stdout)
+	table.SetHeader([]string{"Package", "Version", abg.Trans("subsystems.labels.name"), "Stack", "Package manager"})
+	for _, match := range matches {
+		for i, offer := range match.Offers {
+			name := match.Name
+			if i > 0 {
+				name = ""
+			}
+			table.Append([]string{name, offer.Version, offer.SubSystem, offer.Stack, offer.PkgManager})
+		}
+	}
+	table.Render()
+
+	cmdr.Info.Printfln(abg.Trans("search.info.found"), len(matches))
+
+	return nil
+}
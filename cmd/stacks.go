@@ -95,6 +95,14 @@ func NewStacksCommand() *cmdr.Command {
 			"",
 		),
 	)
+	newCmd.WithStringFlag(
+		cmdr.NewStringFlag(
+			"extends",
+			"e",
+			abg.Trans("stacks.new.options.extends.description"),
+			"",
+		),
+	)
 
 	// Update subcommand
 	updateCmd := cmdr.NewCommand(
@@ -143,6 +151,14 @@ func NewStacksCommand() *cmdr.Command {
 			"",
 		),
 	)
+	updateCmd.WithStringFlag(
+		cmdr.NewStringFlag(
+			"extends",
+			"e",
+			abg.Trans("stacks.update.options.extends.description"),
+			"",
+		),
+	)
 
 	// Rm subcommand
 	rmStackCmd := cmdr.NewCommand(
@@ -267,9 +283,35 @@ func showStack(cmd *cobra.Command, args []string) error {
 
 	table := core.CreateApxTable(os.Stdout)
 	table.Append([]string{abg.Trans("stacks.labels.name"), stack.Name})
+	if stack.Extends != "" {
+		table.Append([]string{"Extends", stack.Extends})
+	}
 	table.Append([]string{"Base", stack.Base})
 	table.Append([]string{"Packages", strings.Join(stack.Packages, ", ")})
 	table.Append([]string{"Package manager", stack.PkgManager})
+	if stack.Profile != "" {
+		table.Append([]string{"Profile", stack.Profile})
+	}
+	if len(stack.Repositories) > 0 {
+		repos := make([]string, 0, len(stack.Repositories))
+		for _, repo := range stack.Repositories {
+			repos = append(repos, fmt.Sprintf("%s: %s", repo.Name, repo.Source))
+		}
+		table.Append([]string{"Repositories", strings.Join(repos, "\n")})
+	}
+	if len(stack.Mounts) > 0 {
+		mounts := make([]string, 0, len(stack.Mounts))
+		for _, mount := range stack.Mounts {
+			mounts = append(mounts, mount.String())
+		}
+		table.Append([]string{"Mounts", strings.Join(mounts, "\n")})
+	}
+	if len(stack.PostCreate) > 0 {
+		table.Append([]string{"Post-create", strings.Join(stack.PostCreate, "\n")})
+	}
+	if len(stack.PreRemove) > 0 {
+		table.Append([]string{"Pre-remove", strings.Join(stack.PreRemove, "\n")})
+	}
 	table.Render()
 
 	return nil
@@ -281,6 +323,7 @@ func newStack(cmd *cobra.Command, args []string) error {
 	base, _ := cmd.Flags().GetString("base")
 	packages, _ := cmd.Flags().GetString("packages")
 	pkgManager, _ := cmd.Flags().GetString("pkg-manager")
+	extends, _ := cmd.Flags().GetString("extends")
 
 	if name == "" {
 		if !noPrompt {
@@ -302,6 +345,22 @@ func newStack(cmd *cobra.Command, args []string) error {
 		return nil
 	}
 
+	// Base and package manager are inherited from the parent unless given
+	if extends != "" {
+		parent, err := core.LoadStack(extends)
+		if err != nil {
+			cmdr.Error.Printfln(abg.Trans("stacks.new.error.parentDoesNotExist"), extends)
+			return nil
+		}
+
+		if base == "" {
+			base = parent.Base
+		}
+		if pkgManager == "" {
+			pkgManager = parent.PkgManager
+		}
+	}
+
 	if base == "" {
 		if !noPrompt {
 			cmdr.Info.Println(abg.Trans("stacks.new.info.askBase"))
@@ -366,6 +425,7 @@ func newStack(cmd *cobra.Command, args []string) error {
 	}
 
 	stack := core.NewStack(name, base, packagesArray, pkgManager, false)
+	stack.Extends = extends
 
 	err := stack.Save()
 	if err != nil {
@@ -383,6 +443,7 @@ func updateStack(cmd *cobra.Command, args []string) error {
 	base, _ := cmd.Flags().GetString("base")
 	packages, _ := cmd.Flags().GetString("packages")
 	pkgManager, _ := cmd.Flags().GetString("pkg-manager")
+	extends, _ := cmd.Flags().GetString("extends")
 
 	if name == "" {
 		if len(args) != 1 || args[0] == "" {
@@ -404,12 +465,25 @@ func updateStack(cmd *cobra.Command, args []string) error {
 		os.Exit(126)
 	}
 
+	// The stack is edited as stored, so the values inherited from its
+	// current parent are not saved in it when the parent changes. An
+	// explicitly empty parent detaches the stack, which keeps the values it
+	// inherited
+	detach := cmd.Flags().Changed("extends") && extends == ""
+	def := stack
+	if !detach {
+		def, error = core.LoadStackDefinition(name)
+		if error != nil {
+			return error
+		}
+	}
+
 	if base == "" {
 		if !noPrompt {
 			cmdr.Info.Printfln(abg.Trans("stacks.update.info.askBase"), stack.Base)
 			fmt.Scanln(&base)
 			if base == "" {
-				base = stack.Base
+				base = def.Base
 			}
 		} else {
 			cmdr.Error.Println(abg.Trans("stacks.update.error.noBase"))
@@ -422,7 +496,7 @@ func updateStack(cmd *cobra.Command, args []string) error {
 			cmdr.Info.Printfln(abg.Trans("stacks.update.info.askPkgManager"), stack.PkgManager)
 			fmt.Scanln(&pkgManager)
 			if pkgManager == "" {
-				pkgManager = stack.PkgManager
+				pkgManager = def.PkgManager
 			}
 		} else {
 			cmdr.Error.Println(abg.Trans("stacks.update.error.noPkgManager"))
@@ -430,17 +504,18 @@ func updateStack(cmd *cobra.Command, args []string) error {
 		}
 	}
 
-	ok := core.PkgManagerExists(pkgManager)
+	// An empty package manager is inherited from the parent
+	ok := pkgManager == "" || core.PkgManagerExists(pkgManager)
 	if !ok {
 		cmdr.Error.Println(abg.Trans("stacks.update.error.pkgManagerDoesNotExist"))
 		return nil
 	}
 
 	if len(packages) > 0 {
-		stack.Packages = strings.Fields(packages)
+		def.Packages = strings.Fields(packages)
 	} else if !noPrompt {
-		if len(stack.Packages) > 0 {
-			cmdr.Info.Println(abg.Trans("stacks.update.info.confirmPackages") + "[y/N]"  + "\n\t -", strings.Join(stack.Packages, "\n\t - "))
+		if len(def.Packages) > 0 {
+			cmdr.Info.Println(abg.Trans("stacks.update.info.confirmPackages") + "[y/N]"  + "\n\t -", strings.Join(def.Packages, "\n\t - "))
 		} else {
 			cmdr.Info.Println(abg.Trans("stacks.update.info.noPackages") + "[y/N]")
 		}
@@ -455,14 +530,18 @@ func updateStack(cmd *cobra.Command, args []string) error {
 			packagesInput, _ := reader.ReadString('\n')
 			packagesInput = strings.TrimSpace(packagesInput)
 			packagesArray = strings.Fields(packagesInput)
-			stack.Packages = packagesArray
+			def.Packages = packagesArray
 		}
 	}
 
-	stack.Base = base
-	stack.PkgManager = pkgManager
+	def.Base = base
+	def.PkgManager = pkgManager
 
-	err := stack.Save()
+	if cmd.Flags().Changed("extends") {
+		def.Extends = extends
+	}
+
+	err := def.Save()
 	if err != nil {
 		return err
 	}
@@ -496,6 +575,18 @@ func removeStack(cmd *cobra.Command, args []string) error {
 		return nil
 	}
 
+	children := core.ListStackChildren(stackName)
+	if len(children) > 0 {
+		cmdr.Error.Printfln(abg.Trans("stacks.rm.error.hasChildren"), len(children))
+		table := core.CreateApxTable(os.Stdout)
+		table.SetHeader([]string{abg.Trans("stacks.labels.name"), "Base", "Pkg manager"})
+		for _, child := range children {
+			table.Append([]string{child.Name, child.Base, child.PkgManager})
+		}
+		table.Render()
+		return nil
+	}
+
 	force, _ := cmd.Flags().GetBool("force")
 
 	if !force {
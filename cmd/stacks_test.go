@@ -0,0 +1,84 @@
+package cmd
+
+import (
+	"slices"
+	"testing"
+
+	"github.com/spf13/cobra"
+
+	"github.com/AuruOS/abg/core"
+)
+
+// newStackUpdateCmd returns a command with the flags updateStack reads, set
+// as given.
+func newStackUpdateCmd(t *testing.T, flags map[string]string) *cobra.Command {
+	t.Helper()
+
+	cmd := &cobra.Command{Use: "update"}
+	cmd.Flags().Bool("no-prompt", false, "")
+	for _, flag := range []string{"name", "base", "packages", "pkg-manager", "extends"} {
+		cmd.Flags().String(flag, "", "")
+	}
+	for flag, value := range flags {
+		err := cmd.Flags().Set(flag, value)
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	return cmd
+}
+
+func TestUpdateStackChangesParent(t *testing.T) {
+	setupRuntimeTest(t)
+
+	stacks := []*core.Stack{
+		{
+			Name:         "old",
+			Base:         "docker.io/library/ubuntu:22.04",
+			PkgManager:   "apt",
+			Packages:     []string{"git"},
+			Repositories: []core.StackRepository{{Name: "old", Source: "deb http://example.com/old jammy main"}},
+			PostCreate:   []string{"echo old"},
+		},
+		{Name: "new", Base: "docker.io/library/ubuntu:22.04", PkgManager: "apt", Packages: []string{"curl"}},
+		{Name: "dev", Extends: "old", Packages: []string{"htop"}},
+	}
+	for _, stack := range stacks {
+		err := stack.Save()
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	cmd := newStackUpdateCmd(t, map[string]string{
+		"name":        "dev",
+		"no-prompt":   "true",
+		"base":        "docker.io/library/ubuntu:22.04",
+		"pkg-manager": "apt",
+		"extends":     "new",
+	})
+	err := updateStack(cmd, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	def, err := core.LoadStackDefinition("dev")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if def.Extends != "new" || !slices.Equal(def.Packages, []string{"htop"}) {
+		t.Errorf("unexpected definition: %+v", def)
+	}
+	if len(def.PostCreate) != 0 || len(def.Repositories) != 0 || def.Base != "" {
+		t.Errorf("values of the old parent were saved in the child: %+v", def)
+	}
+
+	stack, err := core.LoadStack("dev")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !slices.Equal(stack.Packages, []string{"curl", "htop"}) {
+		t.Errorf("resolved packages = %v, want [curl htop]", stack.Packages)
+	}
+}
@@ -37,6 +37,14 @@ func NewSubSystemsCommand() *cmdr.Command {
 			false,
 		),
 	)
+	listCmd.WithBoolFlag(
+		cmdr.NewBoolFlag(
+			"root",
+			"r",
+			abg.Trans("subsystems.list.options.root.description"),
+			false,
+		),
+	)
 
 	// New subcommand
 	newCmd := cmdr.NewCommand(
@@ -78,6 +86,86 @@ func NewSubSystemsCommand() *cmdr.Command {
 			false,
 		),
 	)
+	newCmd.WithBoolFlag(
+		cmdr.NewBoolFlag(
+			"unshared",
+			"u",
+			abg.Trans("subsystems.new.options.unshared.description"),
+			false,
+		),
+	)
+	newCmd.WithBoolFlag(
+		cmdr.NewBoolFlag(
+			"no-nvidia",
+			"",
+			abg.Trans("subsystems.new.options.noNvidia.description"),
+			false,
+		),
+	)
+	newCmd.WithStringFlag(
+		cmdr.NewStringFlag(
+			"profile",
+			"p",
+			abg.Trans("subsystems.new.options.profile.description"),
+			"",
+		),
+	)
+	newCmd.WithStringFlag(
+		cmdr.NewStringFlag(
+			"hostname",
+			"",
+			abg.Trans("subsystems.new.options.hostname.description"),
+			"",
+		),
+	)
+	newCmd.WithStringSliceFlag(
+		cmdr.NewStringSliceFlag(
+			"volume",
+			"",
+			abg.Trans("subsystems.new.options.volume.description"),
+			[]string{},
+		),
+	)
+	newCmd.WithStringSliceFlag(
+		cmdr.NewStringSliceFlag(
+			"tmpfs",
+			"",
+			abg.Trans("subsystems.new.options.tmpfs.description"),
+			[]string{},
+		),
+	)
+	newCmd.WithStringSliceFlag(
+		cmdr.NewStringSliceFlag(
+			"device",
+			"",
+			abg.Trans("subsystems.new.options.device.description"),
+			[]string{},
+		),
+	)
+	newCmd.WithStringSliceFlag(
+		cmdr.NewStringSliceFlag(
+			"env",
+			"e",
+			abg.Trans("subsystems.new.options.env.description"),
+			[]string{},
+		),
+	)
+	newCmd.WithStringSliceFlag(
+		cmdr.NewStringSliceFlag(
+			"engine-flag",
+			"",
+			abg.Trans("subsystems.new.options.engineFlag.description"),
+			[]string{},
+		),
+	)
+	newCmd.WithBoolFlag(
+		cmdr.NewBoolFlag(
+			"root",
+			"r",
+			abg.Trans("subsystems.new.options.root.description"),
+			false,
+		),
+	)
 
 	// Rm subcommand
 	rmCmd := cmdr.NewCommand(
@@ -103,6 +191,14 @@ func NewSubSystemsCommand() *cmdr.Command {
 			false,
 		),
 	)
+	rmCmd.WithBoolFlag(
+		cmdr.NewBoolFlag(
+			"root",
+			"r",
+			abg.Trans("subsystems.rm.options.root.description"),
+			false,
+		),
+	)
 
 	// Reset subcommand
 	resetCmd := cmdr.NewCommand(
@@ -128,20 +224,181 @@ func NewSubSystemsCommand() *cmdr.Command {
 			false,
 		),
 	)
+	resetCmd.WithBoolFlag(
+		cmdr.NewBoolFlag(
+			"clean",
+			"c",
+			abg.Trans("subsystems.reset.options.clean.description"),
+			false,
+		),
+	)
+	resetCmd.WithBoolFlag(
+		cmdr.NewBoolFlag(
+			"root",
+			"r",
+			abg.Trans("subsystems.reset.options.root.description"),
+			false,
+		),
+	)
+
+	// Show subcommand
+	showCmd := cmdr.NewCommand(
+		"show",
+		abg.Trans("subsystems.show.description"),
+		abg.Trans("subsystems.show.description"),
+		showSubSystem,
+	)
+
+	showCmd.WithStringFlag(
+		cmdr.NewStringFlag(
+			"name",
+			"n",
+			abg.Trans("subsystems.show.options.name.description"),
+			"",
+		),
+	)
+	showCmd.WithBoolFlag(
+		cmdr.NewBoolFlag(
+			"root",
+			"r",
+			abg.Trans("subsystems.show.options.root.description"),
+			false,
+		),
+	)
+
+	// Status subcommand
+	statusCmd := cmdr.NewCommand(
+		"status",
+		abg.Trans("subsystems.status.description"),
+		abg.Trans("subsystems.status.description"),
+		statusSubSystems,
+	)
+
+	statusCmd.WithBoolFlag(
+		cmdr.NewBoolFlag(
+			"json",
+			"j",
+			abg.Trans("subsystems.status.options.json.description"),
+			false,
+		),
+	)
+	statusCmd.WithBoolFlag(
+		cmdr.NewBoolFlag(
+			"root",
+			"r",
+			abg.Trans("subsystems.status.options.root.description"),
+			false,
+		),
+	)
+
+	// Diff subcommand
+	diffCmd := cmdr.NewCommand(
+		"diff",
+		abg.Trans("subsystems.diff.description"),
+		abg.Trans("subsystems.diff.description"),
+		diffSubSystem,
+	)
+
+	diffCmd.WithStringFlag(
+		cmdr.NewStringFlag(
+			"name",
+			"n",
+			abg.Trans("subsystems.diff.options.name.description"),
+			"",
+		),
+	)
+	diffCmd.WithBoolFlag(
+		cmdr.NewBoolFlag(
+			"reconcile",
+			"",
+			abg.Trans("subsystems.diff.options.reconcile.description"),
+			false,
+		),
+	)
+	diffCmd.WithBoolFlag(
+		cmdr.NewBoolFlag(
+			"force",
+			"f",
+			abg.Trans("subsystems.diff.options.force.description"),
+			false,
+		),
+	)
+	diffCmd.WithBoolFlag(
+		cmdr.NewBoolFlag(
+			"root",
+			"r",
+			abg.Trans("subsystems.diff.options.root.description"),
+			false,
+		),
+	)
+
+	// Sync subcommand
+	syncCmd := cmdr.NewCommand(
+		"sync",
+		abg.Trans("subsystems.sync.description"),
+		abg.Trans("subsystems.sync.description"),
+		syncSubSystems,
+	)
+
+	syncCmd.WithStringFlag(
+		cmdr.NewStringFlag(
+			"name",
+			"n",
+			abg.Trans("subsystems.sync.options.name.description"),
+			"",
+		),
+	)
+	syncCmd.WithStringFlag(
+		cmdr.NewStringFlag(
+			"stack",
+			"s",
+			abg.Trans("subsystems.sync.options.stack.description"),
+			"",
+		),
+	)
+	syncCmd.WithBoolFlag(
+		cmdr.NewBoolFlag(
+			"prune",
+			"p",
+			abg.Trans("subsystems.sync.options.prune.description"),
+			false,
+		),
+	)
+	syncCmd.WithBoolFlag(
+		cmdr.NewBoolFlag(
+			"force",
+			"f",
+			abg.Trans("subsystems.sync.options.force.description"),
+			false,
+		),
+	)
+	syncCmd.WithBoolFlag(
+		cmdr.NewBoolFlag(
+			"root",
+			"r",
+			abg.Trans("subsystems.sync.options.root.description"),
+			false,
+		),
+	)
 
 	// Add subcommands to subsystems
 	cmd.AddCommand(listCmd)
 	cmd.AddCommand(newCmd)
 	cmd.AddCommand(rmCmd)
 	cmd.AddCommand(resetCmd)
+	cmd.AddCommand(showCmd)
+	cmd.AddCommand(statusCmd)
+	cmd.AddCommand(diffCmd)
+	cmd.AddCommand(syncCmd)
 
 	return cmd
 }
 
 func listSubSystems(cmd *cobra.Command, args []string) error {
 	jsonFlag, _ := cmd.Flags().GetBool("json")
+	rootFlag, _ := cmd.Flags().GetBool("root")
 
-	subSystems, err := core.ListSubSystems(false, false)
+	subSystems, err := core.ListSubSystems(false, rootFlag)
 	if err != nil {
 		return err
 	}
@@ -156,15 +413,35 @@ func listSubSystems(cmd *cobra.Command, args []string) error {
 		cmdr.Info.Printfln(abg.Trans("subsystems.list.info.foundSubsystems"), subSystemsCount)
 
 		table := core.CreateApxTable(os.Stdout)
-		table.SetHeader([]string{abg.Trans("subsystems.labels.name"), "Stack", abg.Trans("subsystems.labels.status"), "Pkgs"})
+		header := []string{abg.Trans("subsystems.labels.name"), "Stack", abg.Trans("subsystems.labels.status"), "Pkgs", "Apps", "Bins"}
+		if rootFlag {
+			header = append(header, "Root")
+		}
+		table.SetHeader(header)
 
 		for _, subSystem := range subSystems {
-			table.Append([]string{
+			apps, bins := 0, 0
+			for _, entry := range subSystem.Exports {
+				switch entry.Type {
+				case core.ExportTypeApp:
+					apps++
+				case core.ExportTypeBin:
+					bins++
+				}
+			}
+
+			row := []string{
 				subSystem.Name,
 				subSystem.Stack.Name,
 				subSystem.Status,
 				fmt.Sprintf("%d", len(subSystem.Stack.Packages)),
-			})
+				fmt.Sprintf("%d", apps),
+				fmt.Sprintf("%d", bins),
+			}
+			if rootFlag {
+				row = append(row, fmt.Sprintf("%t", subSystem.IsRootfull))
+			}
+			table.Append(row)
 		}
 
 		table.Render()
@@ -185,6 +462,34 @@ func newSubSystem(cmd *cobra.Command, args []string) error {
 	stackName, _ := cmd.Flags().GetString("stack")
 	subSystemName, _ := cmd.Flags().GetString("name")
 	isInit, _ := cmd.Flags().GetBool("init")
+	rootFlag, _ := cmd.Flags().GetBool("root")
+	isUnshared, _ := cmd.Flags().GetBool("unshared")
+	noNvidia, _ := cmd.Flags().GetBool("no-nvidia")
+	hostname, _ := cmd.Flags().GetString("hostname")
+	profile, _ := cmd.Flags().GetString("profile")
+	volumes, _ := cmd.Flags().GetStringSlice("volume")
+	tmpfs, _ := cmd.Flags().GetStringSlice("tmpfs")
+	devices, _ := cmd.Flags().GetStringSlice("device")
+	env, _ := cmd.Flags().GetStringSlice("env")
+	engineFlags, _ := cmd.Flags().GetStringSlice("engine-flag")
+
+	if profile != "" {
+		_, err := core.LoadSecurityProfile(profile)
+		if err != nil {
+			cmdr.Error.Println(err)
+			return nil
+		}
+	}
+
+	mounts, err := parseMountFlags(map[string][]string{
+		core.MountTypeBind:   volumes,
+		core.MountTypeTmpfs:  tmpfs,
+		core.MountTypeDevice: devices,
+	})
+	if err != nil {
+		cmdr.Error.Println(err)
+		return nil
+	}
 
 	stacks := core.ListStacks()
 	if len(stacks) == 0 {
@@ -223,12 +528,25 @@ func newSubSystem(cmd *cobra.Command, args []string) error {
 		stackName = stacks[stackIndex-1].Name
 	}
 
-	checkSubSystem, err := core.LoadSubSystem(subSystemName, false)
+	checkSubSystem, err := core.LoadSubSystem(subSystemName, rootFlag)
 	if err == nil {
 		cmdr.Error.Printf(abg.Trans("subsystems.new.error.alreadyExists"), checkSubSystem.Name)
 		return nil
 	}
 
+	// Runtime commands are named after subsystems, so a name can't be used
+	// by both a rootless and a rootful one
+	otherSubSystems, err := core.ListRecordedSubSystems(!rootFlag)
+	if err != nil {
+		return err
+	}
+	for _, otherSubSystem := range otherSubSystems {
+		if otherSubSystem.Name == subSystemName {
+			cmdr.Error.Printf(abg.Trans("subsystems.new.error.alreadyExists"), subSystemName)
+			return nil
+		}
+	}
+
 	for _, existcommand := range cmd.Root().Commands() {
 		if subSystemName == existcommand.Name() {
 			cmdr.Error.Printfln(abg.Trans("subsystems.new.error.forbiddenName"), subSystemName)
@@ -241,10 +559,14 @@ func newSubSystem(cmd *cobra.Command, args []string) error {
 		return err
 	}
 
-	subSystem, err := core.NewSubSystem(subSystemName, stack, home, isInit, false, false, false, true, "")
+	subSystem, err := core.NewSubSystem(subSystemName, stack, home, isInit, false, rootFlag, isUnshared, !noNvidia, hostname)
 	if err != nil {
 		return err
 	}
+	subSystem.Mounts = mounts
+	subSystem.Profile = profile
+	subSystem.Env = env
+	subSystem.EngineFlags = engineFlags
 
 	spinner, _ := cmdr.Spinner.Start(fmt.Sprintf(abg.Trans("subsystems.new.info.creatingSubsystem"), subSystemName, stackName))
 	err = subSystem.Create()
@@ -261,6 +583,7 @@ func newSubSystem(cmd *cobra.Command, args []string) error {
 func rmSubSystem(cmd *cobra.Command, args []string) error {
 	subSystemName, _ := cmd.Flags().GetString("name")
 	forceFlag, _ := cmd.Flags().GetBool("force")
+	rootFlag, _ := cmd.Flags().GetBool("root")
 
 	if subSystemName == "" {
 		cmdr.Error.Println(abg.Trans("subsystems.rm.error.noName"))
@@ -277,16 +600,23 @@ func rmSubSystem(cmd *cobra.Command, args []string) error {
 		}
 	}
 
-	subSystem, err := core.LoadSubSystem(subSystemName, false)
+	subSystem, err := core.LoadSubSystem(subSystemName, rootFlag)
 	if err != nil {
 		return err
 	}
 
-	err = subSystem.Remove()
+	removed, err := subSystem.Remove()
 	if err != nil {
 		return err
 	}
 
+	if len(removed) > 0 {
+		cmdr.Info.Printfln(abg.Trans("subsystems.rm.info.cleanedExports"), len(removed))
+		for _, file := range removed {
+			fmt.Printf("\t- %s\n", file)
+		}
+	}
+
 	cmdr.Success.Printfln(abg.Trans("subsystems.rm.info.success"), subSystemName)
 
 	return nil
@@ -300,6 +630,8 @@ func resetSubSystem(cmd *cobra.Command, args []string) error {
 	}
 
 	forceFlag, _ := cmd.Flags().GetBool("force")
+	cleanFlag, _ := cmd.Flags().GetBool("clean")
+	rootFlag, _ := cmd.Flags().GetBool("root")
 
 	if !forceFlag {
 		cmdr.Info.Printfln(abg.Trans("subsystems.reset.info.askConfirmation")+` [y/N]`, subSystemName)
@@ -311,12 +643,12 @@ func resetSubSystem(cmd *cobra.Command, args []string) error {
 		}
 	}
 
-	subSystem, err := core.LoadSubSystem(subSystemName, false)
+	subSystem, err := core.LoadSubSystem(subSystemName, rootFlag)
 	if err != nil {
 		return err
 	}
 
-	err = subSystem.Reset()
+	err = subSystem.Reset(cleanFlag)
 	if err != nil {
 		return err
 	}
@@ -325,3 +657,301 @@ func resetSubSystem(cmd *cobra.Command, args []string) error {
 
 	return nil
 }
+
+// subSystemStatus is a subsystem and the drift of its stack, as printed by
+// subsystems status --json.
+type subSystemStatus struct {
+	Name   string
+	Stack  string
+	Status string
+	Drift  *core.StackDrift
+}
+
+func statusSubSystems(cmd *cobra.Command, args []string) error {
+	jsonFlag, _ := cmd.Flags().GetBool("json")
+	rootFlag, _ := cmd.Flags().GetBool("root")
+
+	subSystems, err := core.ListSubSystems(false, rootFlag)
+	if err != nil {
+		return err
+	}
+
+	statuses := make([]*subSystemStatus, 0, len(subSystems))
+	for _, subSystem := range subSystems {
+		drift, err := subSystem.StackDrift()
+		if err != nil {
+			return err
+		}
+
+		statuses = append(statuses, &subSystemStatus{
+			Name:   subSystem.Name,
+			Stack:  subSystem.Stack.Name,
+			Status: subSystem.Status,
+			Drift:  drift,
+		})
+	}
+
+	if jsonFlag {
+		jsonStatuses, err := json.MarshalIndent(statuses, "", "  ")
+		if err != nil {
+			return err
+		}
+
+		fmt.Println(string(jsonStatuses))
+		return nil
+	}
+
+	if len(statuses) == 0 {
+		cmdr.Info.Println(abg.Trans("subsystems.list.info.noSubsystems"))
+		return nil
+	}
+
+	table := core.CreateApxTable(os.Stdout)
+	table.SetHeader([]string{abg.Trans("subsystems.labels.name"), "Stack", abg.Trans("subsystems.labels.status"), abg.Trans("subsystems.status.labels.stack")})
+
+	for _, status := range statuses {
+		stackStatus := abg.Trans("subsystems.status.labels.upToDate")
+		switch {
+		case status.Drift.StackDeleted:
+			stackStatus = abg.Trans("subsystems.status.labels.stackDeleted")
+		case status.Drift.NeedsReset():
+			stackStatus = abg.Trans("subsystems.status.labels.needsReset")
+		case status.Drift.HasDrift():
+			stackStatus = fmt.Sprintf(abg.Trans("subsystems.status.labels.packages"), len(status.Drift.AddedPackages), len(status.Drift.RemovedPackages))
+		}
+
+		table.Append([]string{status.Name, status.Stack, status.Status, stackStatus})
+	}
+
+	table.Render()
+
+	return nil
+}
+
+func diffSubSystem(cmd *cobra.Command, args []string) error {
+	subSystemName, _ := cmd.Flags().GetString("name")
+	reconcileFlag, _ := cmd.Flags().GetBool("reconcile")
+	forceFlag, _ := cmd.Flags().GetBool("force")
+	rootFlag, _ := cmd.Flags().GetBool("root")
+
+	if subSystemName == "" {
+		cmdr.Error.Println(abg.Trans("subsystems.diff.error.noName"))
+		return nil
+	}
+
+	subSystem, err := core.LoadSubSystem(subSystemName, rootFlag)
+	if err != nil {
+		return err
+	}
+
+	drift, err := subSystem.StackDrift()
+	if err != nil {
+		return err
+	}
+
+	if !drift.HasDrift() {
+		cmdr.Info.Printfln(abg.Trans("subsystems.diff.info.upToDate"), subSystemName, subSystem.Stack.Name)
+		return nil
+	}
+
+	cmdr.Info.Printfln(abg.Trans("subsystems.diff.info.drifted"), subSystemName, subSystem.Stack.Name)
+	for _, detail := range drift.Details() {
+		fmt.Printf("\t%s\n", detail)
+	}
+
+	if !reconcileFlag {
+		return nil
+	}
+
+	if drift.StackDeleted {
+		cmdr.Error.Printfln(abg.Trans("subsystems.diff.error.stackDeleted"), subSystem.Stack.Name)
+		return nil
+	}
+
+	if drift.NeedsReset() && !forceFlag {
+		cmdr.Info.Printfln(abg.Trans("subsystems.diff.info.askReset")+` [y/N]`, subSystemName)
+		var confirmation string
+		fmt.Scanln(&confirmation)
+		if strings.ToLower(confirmation) != "y" {
+			cmdr.Info.Println(abg.Trans("abg.info.aborting"))
+			return nil
+		}
+	}
+
+	_, err = subSystem.Reconcile(true)
+	if err != nil {
+		return err
+	}
+
+	cmdr.Success.Printfln(abg.Trans("subsystems.diff.info.reconciled"), subSystemName)
+
+	return nil
+}
+
+func syncSubSystems(cmd *cobra.Command, args []string) error {
+	subSystemName, _ := cmd.Flags().GetString("name")
+	stackName, _ := cmd.Flags().GetString("stack")
+	pruneFlag, _ := cmd.Flags().GetBool("prune")
+	forceFlag, _ := cmd.Flags().GetBool("force")
+	rootFlag, _ := cmd.Flags().GetBool("root")
+
+	if (subSystemName == "") == (stackName == "") {
+		cmdr.Error.Println(abg.Trans("subsystems.sync.error.nameOrStack"))
+		return nil
+	}
+
+	var subSystems []*core.SubSystem
+	if subSystemName != "" {
+		subSystem, err := core.LoadSubSystem(subSystemName, rootFlag)
+		if err != nil {
+			return err
+		}
+		subSystems = append(subSystems, subSystem)
+	} else {
+		var err error
+		subSystems, err = core.ListSubsystemForStack(stackName)
+		if err != nil {
+			return err
+		}
+	}
+
+	// Show the plan first, only drifted subsystems are synced
+	drifted := make([]*core.SubSystem, 0)
+	needsReset := false
+	for _, subSystem := range subSystems {
+		drift, err := subSystem.StackDrift()
+		if err != nil {
+			return err
+		}
+
+		if !drift.HasDrift() {
+			cmdr.Info.Printfln(abg.Trans("subsystems.diff.info.upToDate"), subSystem.Name, subSystem.Stack.Name)
+			continue
+		}
+
+		if drift.StackDeleted {
+			cmdr.Warning.Printfln(abg.Trans("subsystems.diff.error.stackDeleted"), subSystem.Stack.Name)
+			continue
+		}
+
+		cmdr.Info.Printfln(abg.Trans("subsystems.diff.info.drifted"), subSystem.Name, subSystem.Stack.Name)
+		for _, detail := range drift.Details() {
+			if strings.HasPrefix(detail, "- ") && !pruneFlag {
+				detail += " " + abg.Trans("subsystems.sync.info.kept")
+			}
+			fmt.Printf("\t%s\n", detail)
+		}
+		if drift.NeedsReset() {
+			fmt.Printf("\t%s\n", abg.Trans("subsystems.sync.info.recreate"))
+			needsReset = true
+		}
+
+		drifted = append(drifted, subSystem)
+	}
+
+	if len(drifted) == 0 {
+		return nil
+	}
+
+	if !forceFlag {
+		question := abg.Trans("subsystems.sync.info.askConfirmation")
+		if needsReset {
+			question = abg.Trans("subsystems.sync.info.askConfirmationReset")
+		}
+
+		cmdr.Info.Printfln(question+` [y/N]`, len(drifted))
+		var confirmation string
+		fmt.Scanln(&confirmation)
+		if strings.ToLower(confirmation) != "y" {
+			cmdr.Info.Println(abg.Trans("abg.info.aborting"))
+			return nil
+		}
+	}
+
+	for _, subSystem := range drifted {
+		spinner, _ := cmdr.Spinner.Start(fmt.Sprintf(abg.Trans("subsystems.sync.info.syncing"), subSystem.Name))
+		_, err := subSystem.Reconcile(pruneFlag)
+		if err != nil {
+			spinner.Fail()
+			return err
+		}
+
+		spinner.UpdateText(fmt.Sprintf(abg.Trans("subsystems.diff.info.reconciled"), subSystem.Name))
+		spinner.Success()
+	}
+
+	return nil
+}
+
+// parseMountFlags parses the mounts given on the command line, keyed by
+// mount type, in the bind, tmpfs and device order.
+func parseMountFlags(values map[string][]string) ([]core.Mount, error) {
+	mounts := make([]core.Mount, 0)
+	for _, mountType := range []string{core.MountTypeBind, core.MountTypeTmpfs, core.MountTypeDevice} {
+		for _, value := range values[mountType] {
+			mount, err := core.ParseMount(mountType, value)
+			if err != nil {
+				return nil, err
+			}
+			mounts = append(mounts, mount)
+		}
+	}
+
+	return mounts, nil
+}
+
+func showSubSystem(cmd *cobra.Command, args []string) error {
+	subSystemName, _ := cmd.Flags().GetString("name")
+	rootFlag, _ := cmd.Flags().GetBool("root")
+
+	if subSystemName == "" {
+		cmdr.Error.Println(abg.Trans("subsystems.show.error.noName"))
+		return nil
+	}
+
+	subSystem, err := core.LoadSubSystem(subSystemName, rootFlag)
+	if err != nil {
+		return err
+	}
+
+	table := core.CreateApxTable(os.Stdout)
+	table.Append([]string{abg.Trans("subsystems.labels.name"), subSystem.Name})
+	table.Append([]string{"Stack", subSystem.Stack.Name})
+	table.Append([]string{abg.Trans("subsystems.labels.status"), subSystem.Status})
+	table.Append([]string{"Root", fmt.Sprintf("%t", subSystem.IsRootfull)})
+	if profile, err := subSystem.SecurityProfile(); err == nil {
+		table.Append([]string{"Profile", fmt.Sprintf("%s: %s", profile.Name, profile.Description)})
+	}
+	table.Append([]string{"Init", fmt.Sprintf("%t", subSystem.HasInit)})
+	table.Append([]string{"Unshared", fmt.Sprintf("%t", subSystem.IsUnshared)})
+	table.Append([]string{"NVIDIA", fmt.Sprintf("%t", subSystem.HasNvidiaIntegration)})
+	if subSystem.Home != "" {
+		table.Append([]string{"Home", subSystem.Home})
+	}
+	if subSystem.Hostname != "" {
+		table.Append([]string{"Hostname", subSystem.Hostname})
+	}
+	if !subSystem.CreatedAt.IsZero() {
+		table.Append([]string{"Created", subSystem.CreatedAt.Format("2006-01-02 15:04")})
+	}
+
+	mounts := make([]string, 0)
+	for _, mount := range subSystem.Stack.Mounts {
+		mounts = append(mounts, mount.String()+" (stack)")
+	}
+	for _, mount := range subSystem.Mounts {
+		mounts = append(mounts, mount.String())
+	}
+	if len(mounts) > 0 {
+		table.Append([]string{"Mounts", strings.Join(mounts, "\n")})
+	}
+	if len(subSystem.Env) > 0 {
+		table.Append([]string{"Environment", strings.Join(subSystem.Env, "\n")})
+	}
+	if len(subSystem.EngineFlags) > 0 {
+		table.Append([]string{"Engine flags", strings.Join(subSystem.EngineFlags, " ")})
+	}
+	table.Render()
+
+	return nil
+}
@@ -0,0 +1,74 @@
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/spf13/cobra"
+
+	"github.com/AuruOS/abg/core"
+	"github.com/AuruOS/orchid/cmdr"
+)
+
+func NewWhichCommand() *cmdr.Command {
+	cmd := cmdr.NewCommand(
+		"which",
+		abg.Trans("which.description"),
+		abg.Trans("which.description"),
+		whichCommand,
+	)
+	cmd.Args = cobra.ExactArgs(1)
+	cmd.WithBoolFlag(
+		cmdr.NewBoolFlag(
+			"json",
+			"j",
+			abg.Trans("which.options.json.description"),
+			false,
+		),
+	)
+
+	return cmd
+}
+
+func whichCommand(cmd *cobra.Command, args []string) error {
+	jsonFlag, _ := cmd.Flags().GetBool("json")
+
+	results, err := core.Which(args[0])
+	if err != nil {
+		return err
+	}
+
+	if jsonFlag {
+		jsonResults, err := json.MarshalIndent(results, "", "  ")
+		if err != nil {
+			return err
+		}
+
+		fmt.Println(string(jsonResults))
+		return nil
+	}
+
+	if len(results) == 0 {
+		cmdr.Info.Printfln(abg.Trans("which.info.notFound"), args[0])
+		return nil
+	}
+
+	table := core.CreateApxTable(os.Stdout)
+	table.SetHeader([]string{abg.Trans("subsystems.labels.name"), "Stack", "Path", "Package", "Version", "Host files", "Collision copy"})
+	for _, result := range results {
+		table.Append([]string{
+			result.SubSystem,
+			result.Stack,
+			result.Path,
+			result.Package,
+			result.Version,
+			strings.Join(result.HostFiles, "\n"),
+			fmt.Sprintf("%t", result.CollisionCopy),
+		})
+	}
+	table.Render()
+
+	return nil
+}
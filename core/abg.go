@@ -2,7 +2,7 @@ package core
 
 import (
 	"fmt"
-	"github.com/AuruOS/apx/v2/settings"
+	"github.com/AuruOS/abg/settings"
 )
 
 var abg *Abg
@@ -27,7 +27,7 @@ func NewAbg(cnf *settings.Config) *Abg {
 }
 
 func NewStandardAbg() *Abg {
-	cnf, err := settings.GetApxDefaultConfig()
+	cnf, err := settings.GetAbgDefaultConfig()
 	if err != nil {
 		panic(err)
 	}
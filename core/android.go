@@ -0,0 +1,428 @@
+package core
+
+import (
+	"errors"
+	"fmt"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+)
+
+// androidBaseImage is the image used for Android subsystems, Waydroid is
+// available from the official Fedora repositories.
+const androidBaseImage = "registry.fedoraproject.org/fedora-toolbox:latest"
+
+// androidPackages are installed in every Android subsystem at creation.
+var androidPackages = []string{"waydroid"}
+
+// AndroidSubSystem represents an Android environment, a container running
+// Waydroid in which APKs are installed, launched and exported to the host.
+type AndroidSubSystem struct {
+	InternalName     string
+	Name             string
+	Status           string
+	IsRootfull       bool
+	ExportedPrograms map[string]map[string]string
+}
+
+// AndroidApp represents an application installed in an Android subsystem.
+type AndroidApp struct {
+	Name    string
+	Package string
+}
+
+// NewAndroidSubSystem creates a new AndroidSubSystem instance.
+func NewAndroidSubSystem(name string, isRootFull bool) (*AndroidSubSystem, error) {
+	if name == "" {
+		return nil, errors.New("name is required")
+	}
+
+	return &AndroidSubSystem{
+		InternalName: genInternalName(name),
+		Name:         name,
+		IsRootfull:   isRootFull,
+	}, nil
+}
+
+// Create creates the Android subsystem container and initializes Waydroid
+// inside it. If the initialization fails, the container is removed.
+func (a *AndroidSubSystem) Create() error {
+	backend, err := NewBackend()
+	if err != nil {
+		return err
+	}
+
+	labels := map[string]string{
+		"name":    a.Name,
+		"android": "true",
+		"hasInit": "true",
+	}
+
+	packages := androidPackages
+	if !backend.InstallsPackages() {
+		packages = nil
+	}
+
+	err = backend.CreateContainer(
+		a.InternalName,
+		androidBaseImage,
+		packages,
+		"",
+		labels,
+		true,
+		a.IsRootfull,
+		false,
+		false,
+		"",
+		ContainerExtras{},
+	)
+	if err != nil {
+		return err
+	}
+
+	if packages == nil {
+		install := append([]string{"sudo", "dnf", "install", "-y"}, androidPackages...)
+		_, err = backend.ContainerExec(a.InternalName, false, false, a.IsRootfull, false, install...)
+		if err != nil {
+			_ = backend.ContainerDelete(a.InternalName, a.IsRootfull)
+			return fmt.Errorf("failed to install waydroid: %w", err)
+		}
+	}
+
+	_, err = backend.ContainerExec(a.InternalName, false, false, a.IsRootfull, false, "sudo", "waydroid", "init")
+	if err != nil {
+		_ = backend.ContainerDelete(a.InternalName, a.IsRootfull)
+		return fmt.Errorf("failed to initialize waydroid: %w", err)
+	}
+
+	return nil
+}
+
+// LoadAndroidSubSystem loads an Android subsystem by name.
+func LoadAndroidSubSystem(name string, isRootFull bool) (*AndroidSubSystem, error) {
+	backend, err := NewBackend()
+	if err != nil {
+		return nil, err
+	}
+
+	internalName := genInternalName(name)
+	container, err := backend.GetContainer(internalName, isRootFull)
+	if err != nil {
+		return nil, err
+	}
+
+	if container.Labels["android"] != "true" {
+		return nil, errors.New("not an android subsystem")
+	}
+
+	return &AndroidSubSystem{
+		InternalName: internalName,
+		Name:         container.Labels["name"],
+		Status:       container.Status,
+		IsRootfull:   isRootFull,
+	}, nil
+}
+
+// ListAndroidSubSystems returns a list of all Android subsystems.
+func ListAndroidSubSystems(includeRootFull bool) ([]*AndroidSubSystem, error) {
+	backend, err := NewBackend()
+	if err != nil {
+		return nil, err
+	}
+
+	containers, err := backend.ListContainers(includeRootFull)
+	if err != nil {
+		return nil, err
+	}
+
+	exports, err := loadExportRegistry()
+	if err != nil {
+		return nil, err
+	}
+
+	subsystems := make([]*AndroidSubSystem, 0)
+	for _, container := range containers {
+		if container.Labels["android"] != "true" {
+			continue
+		}
+
+		internalName := genInternalName(container.Labels["name"])
+		subsystems = append(subsystems, &AndroidSubSystem{
+			InternalName:     internalName,
+			Name:             container.Labels["name"],
+			Status:           container.Status,
+			IsRootfull:       includeRootFull,
+			ExportedPrograms: exportedPrograms(exports[storageName(internalName, includeRootFull)]),
+		})
+	}
+
+	return subsystems, nil
+}
+
+// Exec executes a command in the Android subsystem.
+func (a *AndroidSubSystem) Exec(captureOutput bool, args ...string) (string, error) {
+	backend, err := NewBackend()
+	if err != nil {
+		return "", err
+	}
+
+	return backend.ContainerExec(a.InternalName, captureOutput, false, a.IsRootfull, false, args...)
+}
+
+// androidSessionTimeout is how long startSession waits for the Waydroid
+// session to run.
+var androidSessionTimeout = 60 * time.Second
+
+// androidSessions are the subsystems whose Waydroid session is known to
+// run, keyed by internal name, so it is started once per process.
+var (
+	androidSessions   = map[string]bool{}
+	androidSessionsMu sync.Mutex
+)
+
+// sessionRunning informs whether `waydroid status` reports a running
+// session.
+func (a *AndroidSubSystem) sessionRunning(backend Backend) bool {
+	out, err := backend.ContainerQuery(a.InternalName, a.IsRootfull, "waydroid", "status")
+	if err != nil {
+		return false
+	}
+
+	for _, line := range strings.Split(out, "\n") {
+		key, value, ok := strings.Cut(line, ":")
+		if ok && strings.TrimSpace(key) == "Session" {
+			return strings.TrimSpace(value) == "RUNNING"
+		}
+	}
+
+	return false
+}
+
+// startSession starts the Waydroid session, required by every app command,
+// and waits until it runs.
+func (a *AndroidSubSystem) startSession() error {
+	androidSessionsMu.Lock()
+	defer androidSessionsMu.Unlock()
+
+	if androidSessions[a.InternalName] {
+		return nil
+	}
+
+	backend, err := NewBackend()
+	if err != nil {
+		return err
+	}
+
+	if a.sessionRunning(backend) {
+		androidSessions[a.InternalName] = true
+		return nil
+	}
+
+	_, err = backend.ContainerExec(a.InternalName, false, true, a.IsRootfull, true, "waydroid", "session", "start")
+	if err != nil {
+		return err
+	}
+
+	// Nothing was started to wait for
+	if IsDryRun() {
+		return nil
+	}
+
+	deadline := time.Now().Add(androidSessionTimeout)
+	for !a.sessionRunning(backend) {
+		if time.Now().After(deadline) {
+			return errors.New("timed out waiting for the waydroid session to start")
+		}
+		time.Sleep(500 * time.Millisecond)
+	}
+
+	androidSessions[a.InternalName] = true
+	return nil
+}
+
+// forgetSession makes the next app command start the Waydroid session
+// again, as it ends with the container.
+func (a *AndroidSubSystem) forgetSession() {
+	androidSessionsMu.Lock()
+	defer androidSessionsMu.Unlock()
+
+	delete(androidSessions, a.InternalName)
+}
+
+// Install installs an APK from the host and exports its desktop entry,
+// returning the package name of the installed app.
+func (a *AndroidSubSystem) Install(apkPath string, export bool) (string, error) {
+	apkPath, err := filepath.Abs(apkPath)
+	if err != nil {
+		return "", err
+	}
+
+	// The package is read from the APK rather than from the installed apps,
+	// which already list it when an app is reinstalled or updated
+	pkg, err := apkPackageName(apkPath)
+	if err != nil {
+		return "", fmt.Errorf("unable to read the package name of %s: %w", apkPath, err)
+	}
+
+	if err := a.startSession(); err != nil {
+		return "", err
+	}
+
+	if _, err := a.Exec(false, "waydroid", "app", "install", apkPath); err != nil {
+		return "", err
+	}
+
+	if export {
+		if err := a.ExportDesktopEntry(pkg); err != nil {
+			return pkg, err
+		}
+	}
+
+	return pkg, nil
+}
+
+// Remove removes an installed app and its exported desktop entry.
+func (a *AndroidSubSystem) Remove(pkg string) error {
+	if err := a.startSession(); err != nil {
+		return err
+	}
+
+	_ = a.UnexportDesktopEntry(pkg)
+
+	_, err := a.Exec(false, "waydroid", "app", "remove", pkg)
+	return err
+}
+
+// Run launches an installed app.
+func (a *AndroidSubSystem) Run(pkg string) error {
+	if err := a.startSession(); err != nil {
+		return err
+	}
+
+	_, err := a.Exec(false, "waydroid", "app", "launch", pkg)
+	return err
+}
+
+// ListApps returns the apps installed in the Android subsystem.
+func (a *AndroidSubSystem) ListApps() ([]AndroidApp, error) {
+	if err := a.startSession(); err != nil {
+		return nil, err
+	}
+
+	out, err := a.Exec(true, "waydroid", "app", "list")
+	if err != nil {
+		return nil, err
+	}
+
+	return parseWaydroidAppList(out), nil
+}
+
+// parseWaydroidAppList parses the output of `waydroid app list`, which
+// prints a "Name:" and a "packageName:" line for each app.
+func parseWaydroidAppList(out string) []AndroidApp {
+	apps := make([]AndroidApp, 0)
+	current := AndroidApp{}
+
+	for _, line := range strings.Split(out, "\n") {
+		line = strings.TrimSpace(line)
+		switch {
+		case strings.HasPrefix(line, "Name:"):
+			current.Name = strings.TrimSpace(strings.TrimPrefix(line, "Name:"))
+		case strings.HasPrefix(line, "packageName:"):
+			current.Package = strings.TrimSpace(strings.TrimPrefix(line, "packageName:"))
+			apps = append(apps, current)
+			current = AndroidApp{}
+		}
+	}
+
+	return apps
+}
+
+// ExportDesktopEntry exports the desktop entry Waydroid generates for an
+// app, recording the written files in the export registry.
+func (a *AndroidSubSystem) ExportDesktopEntry(pkg string) error {
+	backend, err := NewBackend()
+	if err != nil {
+		return err
+	}
+
+	appsDir, err := hostApplicationsDir()
+	if err != nil {
+		return err
+	}
+	before := snapshotDir(appsDir)
+
+	err = backend.ContainerExportDesktopEntry(a.InternalName, "waydroid."+pkg, fmt.Sprintf("on %s", a.Name), a.IsRootfull)
+	if err != nil {
+		return err
+	}
+
+	return registerExport(storageName(a.InternalName, a.IsRootfull), &ExportEntry{
+		Type:       ExportTypeApp,
+		Name:       "waydroid." + pkg,
+		HostFiles:  changedFiles(appsDir, before),
+		ExportedAt: time.Now(),
+	})
+}
+
+// UnexportDesktopEntry removes the exported desktop entry of an app.
+func (a *AndroidSubSystem) UnexportDesktopEntry(pkg string) error {
+	backend, err := NewBackend()
+	if err != nil {
+		return err
+	}
+
+	err = backend.ContainerUnexportDesktopEntry(a.InternalName, "waydroid."+pkg, a.IsRootfull)
+	if err != nil {
+		return err
+	}
+
+	return unregisterExport(storageName(a.InternalName, a.IsRootfull), ExportTypeApp, "waydroid."+pkg)
+}
+
+// LoadExports returns the desktop entries exported from the Android
+// subsystem.
+func (a *AndroidSubSystem) LoadExports() ([]*ExportEntry, error) {
+	return loadExports(storageName(a.InternalName, a.IsRootfull))
+}
+
+// Start starts the Android subsystem.
+func (a *AndroidSubSystem) Start() error {
+	backend, err := NewBackend()
+	if err != nil {
+		return err
+	}
+
+	return backend.ContainerStart(a.InternalName, a.IsRootfull)
+}
+
+// Stop stops the Android subsystem.
+func (a *AndroidSubSystem) Stop() error {
+	backend, err := NewBackend()
+	if err != nil {
+		return err
+	}
+
+	a.forgetSession()
+	return backend.ContainerStop(a.InternalName, a.IsRootfull)
+}
+
+// Delete deletes the Android subsystem and the desktop entries exported
+// from it. The exports are found in the registry, so the Waydroid session
+// is not started to list the apps.
+func (a *AndroidSubSystem) Delete() error {
+	backend, err := NewBackend()
+	if err != nil {
+		return err
+	}
+
+	a.forgetSession()
+	err = backend.ContainerDelete(a.InternalName, a.IsRootfull)
+	if err != nil {
+		return err
+	}
+
+	_, err = removeExportedFiles(storageName(a.InternalName, a.IsRootfull))
+	return err
+}
@@ -0,0 +1,202 @@
+package core
+
+import (
+	"archive/zip"
+	"bytes"
+	"encoding/binary"
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+)
+
+// writeTestAPK writes an APK whose binary manifest declares the package.
+func writeTestAPK(t *testing.T, path, pkg string) {
+	t.Helper()
+
+	le := binary.LittleEndian
+	strs := []string{"manifest", "package", pkg}
+
+	var data bytes.Buffer
+	offsets := make([]uint32, 0, len(strs))
+	for _, str := range strs {
+		offsets = append(offsets, uint32(data.Len()))
+		data.Write([]byte{byte(len(str)), byte(len(str))})
+		data.WriteString(str)
+		data.WriteByte(0)
+	}
+	for data.Len()%4 != 0 {
+		data.WriteByte(0)
+	}
+
+	var pool bytes.Buffer
+	poolHeaderSize := 28 + 4*len(strs)
+	binary.Write(&pool, le, []uint16{axmlStringPoolType, 28})
+	binary.Write(&pool, le, []uint32{uint32(poolHeaderSize + data.Len()), uint32(len(strs)), 0, axmlUTF8Flag, uint32(poolHeaderSize), 0})
+	binary.Write(&pool, le, offsets)
+	pool.Write(data.Bytes())
+
+	// <manifest package="pkg">, its name and attribute referring to the pool
+	var element bytes.Buffer
+	binary.Write(&element, le, []uint16{axmlStartElementType, 16})
+	binary.Write(&element, le, []uint32{56, 1, 0xffffffff})
+	binary.Write(&element, le, []uint32{0xffffffff, 0})
+	binary.Write(&element, le, []uint16{20, 20, 1, 0, 0, 0})
+	binary.Write(&element, le, []uint32{0xffffffff, 1, 2})
+	binary.Write(&element, le, []uint16{8})
+	binary.Write(&element, le, []uint8{0, 0x03})
+	binary.Write(&element, le, []uint32{2})
+
+	var manifest bytes.Buffer
+	binary.Write(&manifest, le, []uint16{0x0003, 8})
+	binary.Write(&manifest, le, []uint32{uint32(8 + pool.Len() + element.Len())})
+	manifest.Write(pool.Bytes())
+	manifest.Write(element.Bytes())
+
+	var apk bytes.Buffer
+	archive := zip.NewWriter(&apk)
+	file, err := archive.Create("AndroidManifest.xml")
+	if err == nil {
+		_, err = file.Write(manifest.Bytes())
+	}
+	if err == nil {
+		err = archive.Close()
+	}
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	writeTestFile(t, path, apk.String())
+}
+
+func TestAndroidInstallReinstall(t *testing.T) {
+	fake := setupTestAbg(t)
+
+	android, err := NewAndroidSubSystem("phone", false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(android.forgetSession)
+
+	appsDir, err := hostApplicationsDir()
+	if err != nil {
+		t.Fatal(err)
+	}
+	desktopFile := filepath.Join(appsDir, "abg-phone-waydroid.org.example.app.desktop")
+
+	apk := filepath.Join(t.TempDir(), "app.apk")
+	writeTestAPK(t, apk, "org.example.app")
+	fake.Outputs["waydroid status"] = "Session:\tRUNNING\n"
+	// The app is listed before the install, as when it is updated
+	fake.Outputs["waydroid app list"] = "Name: App\npackageName: org.example.app\n"
+	fake.HostFiles["waydroid.org.example.app on phone"] = map[string]string{desktopFile: "[Desktop Entry]\n"}
+
+	for range 2 {
+		pkg, err := android.Install(apk, true)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if pkg != "org.example.app" {
+			t.Errorf("installed package %q, want org.example.app", pkg)
+		}
+	}
+
+	exports, err := android.LoadExports()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(exports) != 1 || exports[0].Name != "waydroid.org.example.app" || !slices.Equal(exports[0].HostFiles, []string{desktopFile}) {
+		t.Errorf("unexpected exports: %+v", exports)
+	}
+}
+
+func TestAndroidDeleteRemovesExportsWithoutSession(t *testing.T) {
+	fake := setupTestAbg(t)
+
+	android, err := NewAndroidSubSystem("phone", false)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	desktopFile := filepath.Join(t.TempDir(), "abg-phone-waydroid.org.example.app.desktop")
+	writeTestFile(t, desktopFile, "[Desktop Entry]\n")
+	err = registerExport(android.InternalName, &ExportEntry{Type: ExportTypeApp, Name: "waydroid.org.example.app", HostFiles: []string{desktopFile}})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = android.Delete()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if calls := append(fake.CallsTo("query"), fake.CallsTo("exec")...); len(calls) != 0 {
+		t.Errorf("deleting should not run commands in the container, got %+v", calls)
+	}
+	if len(fake.CallsTo("delete")) != 1 {
+		t.Error("the container was not deleted")
+	}
+	if _, err := os.Stat(desktopFile); !os.IsNotExist(err) {
+		t.Error("the exported desktop entry was not removed")
+	}
+
+	exports, err := android.LoadExports()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(exports) != 0 {
+		t.Errorf("the exports were not forgotten: %+v", exports)
+	}
+}
+
+func TestAndroidSessionStartedOnce(t *testing.T) {
+	fake := setupTestAbg(t)
+
+	android, err := NewAndroidSubSystem("phone", false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(android.forgetSession)
+
+	fake.Outputs["waydroid status"] = "Session:\tRUNNING\nContainer:\tRUNNING\n"
+	for _, pkg := range []string{"org.example.one", "org.example.two"} {
+		err = android.Run(pkg)
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if queries := fake.CallsTo("query"); len(queries) != 1 {
+		t.Errorf("the session status should be checked once, got %d queries", len(queries))
+	}
+	for _, call := range fake.CallsTo("exec") {
+		if slices.Equal(call.Args, []string{"waydroid", "session", "start"}) {
+			t.Error("a running session should not be started again")
+		}
+	}
+}
+
+func TestAndroidSessionStartTimeout(t *testing.T) {
+	fake := setupTestAbg(t)
+
+	timeout := androidSessionTimeout
+	androidSessionTimeout = 0
+	t.Cleanup(func() { androidSessionTimeout = timeout })
+
+	android, err := NewAndroidSubSystem("phone", false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(android.forgetSession)
+
+	fake.Outputs["waydroid status"] = "Session:\tSTOPPED\n"
+	err = android.Run("org.example.one")
+	if err == nil {
+		t.Fatal("apps should not be launched before the session runs")
+	}
+
+	execs := fake.CallsTo("exec")
+	if len(execs) != 1 || !slices.Equal(execs[0].Args, []string{"waydroid", "session", "start"}) {
+		t.Errorf("expected only the session start, got %+v", execs)
+	}
+}
@@ -0,0 +1,214 @@
+package core
+
+import (
+	"archive/zip"
+	"encoding/binary"
+	"errors"
+	"io"
+	"unicode/utf16"
+)
+
+// Chunk types of the Android binary XML format.
+const (
+	axmlStringPoolType   = 0x0001
+	axmlStartElementType = 0x0102
+)
+
+// axmlUTF8Flag marks a string pool whose strings are UTF-8 encoded.
+const axmlUTF8Flag = 1 << 8
+
+// apkPackageName reads the package name declared by the manifest of an
+// APK, the binary encoded AndroidManifest.xml at the root of the archive.
+func apkPackageName(apkPath string) (string, error) {
+	archive, err := zip.OpenReader(apkPath)
+	if err != nil {
+		return "", err
+	}
+	defer archive.Close()
+
+	manifest, err := archive.Open("AndroidManifest.xml")
+	if err != nil {
+		return "", err
+	}
+	defer manifest.Close()
+
+	data, err := io.ReadAll(manifest)
+	if err != nil {
+		return "", err
+	}
+
+	return parseManifestPackage(data)
+}
+
+// parseManifestPackage returns the package attribute of the manifest
+// element of a binary XML manifest.
+func parseManifestPackage(data []byte) (string, error) {
+	if len(data) < 8 {
+		return "", errors.New("manifest too short")
+	}
+
+	var pool []string
+	offset := int(binary.LittleEndian.Uint16(data[2:4]))
+	for offset+8 <= len(data) {
+		chunkType := binary.LittleEndian.Uint16(data[offset:])
+		headerSize := int(binary.LittleEndian.Uint16(data[offset+2:]))
+		size := int(binary.LittleEndian.Uint32(data[offset+4:]))
+		if size < 8 || offset+size > len(data) {
+			return "", errors.New("malformed manifest chunk")
+		}
+		chunk := data[offset : offset+size]
+
+		switch chunkType {
+		case axmlStringPoolType:
+			var err error
+			pool, err = parseStringPool(chunk)
+			if err != nil {
+				return "", err
+			}
+		case axmlStartElementType:
+			name, attrs, err := parseStartElement(chunk, headerSize, pool)
+			if err != nil {
+				return "", err
+			}
+			if name != "manifest" {
+				return "", errors.New("manifest element not found")
+			}
+			if attrs["package"] == "" {
+				return "", errors.New("manifest declares no package")
+			}
+			return attrs["package"], nil
+		}
+
+		offset += size
+	}
+
+	return "", errors.New("manifest element not found")
+}
+
+// parseStringPool decodes the strings of a string pool chunk.
+func parseStringPool(chunk []byte) ([]string, error) {
+	if len(chunk) < 28 {
+		return nil, errors.New("malformed string pool")
+	}
+
+	headerSize := int(binary.LittleEndian.Uint16(chunk[2:]))
+	count := int(binary.LittleEndian.Uint32(chunk[8:]))
+	flags := binary.LittleEndian.Uint32(chunk[16:])
+	stringsStart := int(binary.LittleEndian.Uint32(chunk[20:]))
+	if headerSize+count*4 > len(chunk) {
+		return nil, errors.New("malformed string pool")
+	}
+
+	pool := make([]string, count)
+	for i := range pool {
+		start := stringsStart + int(binary.LittleEndian.Uint32(chunk[headerSize+i*4:]))
+		if start >= len(chunk) {
+			return nil, errors.New("malformed string pool")
+		}
+
+		var err error
+		if flags&axmlUTF8Flag != 0 {
+			pool[i], err = decodeUTF8PoolString(chunk[start:])
+		} else {
+			pool[i], err = decodeUTF16PoolString(chunk[start:])
+		}
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return pool, nil
+}
+
+// decodeUTF8PoolString decodes a string pool entry prefixed with its
+// length in characters and in bytes, each on one or two bytes.
+func decodeUTF8PoolString(data []byte) (string, error) {
+	pos := 0
+	length := 0
+	for range 2 {
+		if pos >= len(data) {
+			return "", errors.New("malformed string pool")
+		}
+		length = int(data[pos])
+		pos++
+		if length&0x80 != 0 {
+			if pos >= len(data) {
+				return "", errors.New("malformed string pool")
+			}
+			length = (length&0x7f)<<8 | int(data[pos])
+			pos++
+		}
+	}
+
+	if pos+length > len(data) {
+		return "", errors.New("malformed string pool")
+	}
+
+	return string(data[pos : pos+length]), nil
+}
+
+// decodeUTF16PoolString decodes a string pool entry prefixed with its
+// length in UTF-16 units, on one or two units.
+func decodeUTF16PoolString(data []byte) (string, error) {
+	if len(data) < 2 {
+		return "", errors.New("malformed string pool")
+	}
+
+	pos := 2
+	length := int(binary.LittleEndian.Uint16(data))
+	if length&0x8000 != 0 {
+		if len(data) < 4 {
+			return "", errors.New("malformed string pool")
+		}
+		length = (length&0x7fff)<<16 | int(binary.LittleEndian.Uint16(data[2:]))
+		pos = 4
+	}
+
+	if pos+length*2 > len(data) {
+		return "", errors.New("malformed string pool")
+	}
+
+	units := make([]uint16, length)
+	for i := range units {
+		units[i] = binary.LittleEndian.Uint16(data[pos+i*2:])
+	}
+
+	return string(utf16.Decode(units)), nil
+}
+
+// parseStartElement returns the name of a start element chunk and its
+// attributes whose value is a string.
+func parseStartElement(chunk []byte, headerSize int, pool []string) (string, map[string]string, error) {
+	poolString := func(index uint32) string {
+		if int(index) >= len(pool) {
+			return ""
+		}
+		return pool[index]
+	}
+
+	ext := headerSize
+	if ext+20 > len(chunk) {
+		return "", nil, errors.New("malformed manifest element")
+	}
+
+	name := poolString(binary.LittleEndian.Uint32(chunk[ext+4:]))
+	attrStart := int(binary.LittleEndian.Uint16(chunk[ext+8:]))
+	attrSize := int(binary.LittleEndian.Uint16(chunk[ext+10:]))
+	attrCount := int(binary.LittleEndian.Uint16(chunk[ext+12:]))
+	if attrSize < 20 || ext+attrStart+attrCount*attrSize > len(chunk) {
+		return "", nil, errors.New("malformed manifest element")
+	}
+
+	attrs := map[string]string{}
+	for i := range attrCount {
+		attr := chunk[ext+attrStart+i*attrSize:]
+		rawValue := binary.LittleEndian.Uint32(attr[8:])
+		// A string typed value refers to the pool when no raw value is kept
+		if rawValue == 0xffffffff && attr[15] == 0x03 {
+			rawValue = binary.LittleEndian.Uint32(attr[16:])
+		}
+		attrs[poolString(binary.LittleEndian.Uint32(attr[4:]))] = poolString(rawValue)
+	}
+
+	return name, attrs, nil
+}
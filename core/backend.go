@@ -0,0 +1,104 @@
+package core
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Backends selectable with the backend key of abg.json.
+const (
+	BackendDistrobox = "distrobox"
+	BackendPodman    = "podman"
+)
+
+// Backend creates and runs the containers backing subsystems.
+type Backend interface {
+	// InstallsPackages informs whether CreateContainer installs the packages
+	// it is given. Otherwise the caller installs them with the package
+	// manager once the container exists.
+	InstallsPackages() bool
+
+	CreateContainer(name, image string, packages []string, home string, labels map[string]string, withInit, rootFull, unshared, withNvidia bool, hostname string, extras ContainerExtras) error
+	ListContainers(rootFull bool) ([]DBoxContainer, error)
+	GetContainer(name string, rootFull bool) (*DBoxContainer, error)
+
+	// ContainerExec runs a command in the container. A command whose output
+	// is captured gets no input.
+	ContainerExec(name string, captureOutput, muteOutput, rootFull, detached bool, args ...string) (string, error)
+	// ContainerQuery runs a read-only command and returns its output, it
+	// also runs in dry-run mode.
+	ContainerQuery(name string, rootFull bool, args ...string) (string, error)
+	ContainerEnter(name string, rootFull bool) error
+	ContainerStart(name string, rootFull bool) error
+	ContainerStop(name string, rootFull bool) error
+	ContainerDelete(name string, rootFull bool) error
+	// ContainerCommit saves the container filesystem as the given image.
+	ContainerCommit(name, image string, rootFull bool) error
+	ImageDelete(image string, rootFull bool) error
+
+	ContainerExportDesktopEntry(name, app, label string, rootFull bool) error
+	ContainerUnexportDesktopEntry(name, app string, rootFull bool) error
+	ContainerExportBin(name, binary, path string, rootFull bool) error
+	ContainerUnexportBin(name, binary string, rootFull bool) error
+}
+
+// ContainerExtras are the creation options passed to the container engine
+// as they are given.
+type ContainerExtras struct {
+	Volumes     []string // Mounts in the engine --volume format, host:container[:options]
+	Tmpfs       []string // Paths in the container mounted as tmpfs
+	Devices     []string // Device nodes in the engine --device format, host:container
+	Env         []string // Environment variables, KEY=VALUE
+	EngineFlags []string // Flags passed to the engine create command
+
+	// Set from the security profile
+	IsolateHome  bool     // The user home is not mounted
+	Network      string   // One of the Network* modes, host if empty
+	CapDrop      []string // Capabilities dropped from the container
+	ReadOnlyRoot bool     // The root filesystem is read-only
+}
+
+// Validate checks the environment variables are in the KEY=VALUE form.
+func (e ContainerExtras) Validate() error {
+	for _, env := range e.Env {
+		if key, _, ok := strings.Cut(env, "="); !ok || key == "" {
+			return fmt.Errorf("invalid environment variable %q, expected KEY=VALUE", env)
+		}
+	}
+
+	return nil
+}
+
+var (
+	_ Backend = (*DBox)(nil)
+	_ Backend = (*PodmanBackend)(nil)
+)
+
+// backendOverride replaces the configured backend when set, tests use it to
+// run without a container engine.
+var backendOverride Backend
+
+// NewBackend returns the backend selected in the configuration, distrobox
+// being the default.
+func NewBackend() (Backend, error) {
+	if backendOverride != nil {
+		return backendOverride, nil
+	}
+
+	switch abg.Cnf.Backend {
+	case "", BackendDistrobox:
+		dbox, err := NewDBox()
+		if err != nil {
+			return nil, err
+		}
+		return dbox, nil
+	case BackendPodman:
+		podman, err := NewPodmanBackend()
+		if err != nil {
+			return nil, err
+		}
+		return podman, nil
+	}
+
+	return nil, fmt.Errorf("unknown backend %s", abg.Cnf.Backend)
+}
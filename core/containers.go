@@ -0,0 +1,171 @@
+package core
+
+import (
+	"bytes"
+	"encoding/json"
+	"strings"
+)
+
+// podmanContainer is a container as listed by podman ps --format json.
+type podmanContainer struct {
+	ID        string            `json:"Id"`
+	Names     []string          `json:"Names"`
+	Labels    map[string]string `json:"Labels"`
+	State     string            `json:"State"`
+	Status    string            `json:"Status"`
+	CreatedAt string            `json:"CreatedAt"`
+}
+
+// dockerContainer is a container as listed by docker ps --format
+// '{{json .}}'. Its labels are flattened into a comma separated string, so
+// they are read with docker inspect instead.
+type dockerContainer struct {
+	ID        string `json:"ID"`
+	Names     string `json:"Names"`
+	State     string `json:"State"`
+	Status    string `json:"Status"`
+	CreatedAt string `json:"CreatedAt"`
+}
+
+// dockerInspect is the part of docker inspect output holding the labels.
+type dockerInspect struct {
+	ID     string `json:"Id"`
+	Config struct {
+		Labels map[string]string `json:"Labels"`
+	} `json:"Config"`
+}
+
+// listEngineContainers lists every container of the engine, run executes an
+// engine command and returns its output.
+func listEngineContainers(engine string, run func(args ...string) ([]byte, error)) ([]DBoxContainer, error) {
+	if engine != "docker" {
+		output, err := run("ps", "-a", "--format", "json")
+		if err != nil {
+			return nil, err
+		}
+
+		return parsePodmanContainers(output)
+	}
+
+	psOutput, err := run("ps", "-a", "--no-trunc", "--format", "{{json .}}")
+	if err != nil {
+		return nil, err
+	}
+
+	containers, err := parseDockerContainers(psOutput)
+	if err != nil || len(containers) == 0 {
+		return containers, err
+	}
+
+	ids := make([]string, 0, len(containers))
+	for _, c := range containers {
+		ids = append(ids, c.ID)
+	}
+
+	inspectOutput, err := run(append([]string{"inspect"}, ids...)...)
+	if err != nil {
+		return nil, err
+	}
+
+	err = applyDockerLabels(containers, inspectOutput)
+	if err != nil {
+		return nil, err
+	}
+
+	return containers, nil
+}
+
+// parsePodmanContainers parses the output of podman ps --format json.
+func parsePodmanContainers(output []byte) ([]DBoxContainer, error) {
+	var listed []podmanContainer
+	if len(bytes.TrimSpace(output)) > 0 {
+		err := json.Unmarshal(output, &listed)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	containers := make([]DBoxContainer, 0, len(listed))
+	for _, c := range listed {
+		container := DBoxContainer{
+			ID:        c.ID,
+			CreatedAt: c.CreatedAt,
+			Status:    c.Status,
+			Labels:    c.Labels,
+		}
+		if container.Status == "" {
+			container.Status = c.State
+		}
+		if container.Labels == nil {
+			container.Labels = map[string]string{}
+		}
+		if len(c.Names) > 0 {
+			container.Name = c.Names[0]
+		}
+
+		containers = append(containers, container)
+	}
+
+	return containers, nil
+}
+
+// parseDockerContainers parses the output of docker ps --format
+// '{{json .}}', one container per line. Labels are left empty.
+func parseDockerContainers(output []byte) ([]DBoxContainer, error) {
+	containers := make([]DBoxContainer, 0)
+	for _, line := range bytes.Split(output, []byte("\n")) {
+		if len(bytes.TrimSpace(line)) == 0 {
+			continue
+		}
+
+		var c dockerContainer
+		err := json.Unmarshal(line, &c)
+		if err != nil {
+			return nil, err
+		}
+
+		container := DBoxContainer{
+			ID:        c.ID,
+			CreatedAt: c.CreatedAt,
+			Status:    c.Status,
+			Labels:    map[string]string{},
+			Name:      strings.Split(c.Names, ",")[0],
+		}
+		if container.Status == "" {
+			container.Status = c.State
+		}
+
+		containers = append(containers, container)
+	}
+
+	return containers, nil
+}
+
+// applyDockerLabels sets the labels of the containers from the output of
+// docker inspect.
+func applyDockerLabels(containers []DBoxContainer, inspectOutput []byte) error {
+	var inspected []dockerInspect
+	err := json.Unmarshal(inspectOutput, &inspected)
+	if err != nil {
+		return err
+	}
+
+	labels := map[string]map[string]string{}
+	for _, c := range inspected {
+		labels[c.ID] = c.Config.Labels
+	}
+
+	for i := range containers {
+		if containerLabels, ok := labels[containers[i].ID]; ok && containerLabels != nil {
+			containers[i].Labels = containerLabels
+		}
+	}
+
+	return nil
+}
+
+// shellQuote quotes a string for a POSIX shell. Distrobox evaluates the
+// additional engine flags, so they are quoted to reach the engine as is.
+func shellQuote(s string) string {
+	return "'" + strings.ReplaceAll(s, "'", `'\''`) + "'"
+}
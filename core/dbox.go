@@ -1,6 +1,7 @@
 package core
 
 import (
+	"bytes"
 	"errors"
 	"fmt"
 	"log"
@@ -14,6 +15,7 @@ type DBox struct {
 	Engine       string
 	EngineBinary string
 	Version      string
+	DryRun       bool // Print commands changing containers instead of running them
 }
 
 type DBoxContainer struct {
@@ -36,6 +38,7 @@ func NewDBox() (*DBox, error) {
 		Engine:       engine,
 		EngineBinary: engineBinary,
 		Version:      version,
+		DryRun:       IsDryRun(),
 	}, nil
 }
 
@@ -64,7 +67,19 @@ func getDBoxVersion() (string, error) {
 	return strings.TrimSpace(parts[1]), nil
 }
 
+// InstallsPackages informs whether CreateContainer installs the packages it
+// is given, distrobox does it through --additional-packages.
+func (d *DBox) InstallsPackages() bool {
+	return true
+}
+
 func (d *DBox) RunCommand(command string, args, engineFlags []string, useEngine, captureOutput, muteOutput, rootFull, detached bool) ([]byte, error) {
+	return d.runCommand(command, args, engineFlags, useEngine, captureOutput, muteOutput, rootFull, detached, false)
+}
+
+// runCommand runs a distrobox or engine command. Queries don't change
+// anything, so they are run even in dry-run mode.
+func (d *DBox) runCommand(command string, args, engineFlags []string, useEngine, captureOutput, muteOutput, rootFull, detached, query bool) ([]byte, error) {
 	entrypoint := abg.Cnf.DistroboxPath
 	finalArgs := []string{command}
 
@@ -89,7 +104,11 @@ func (d *DBox) RunCommand(command string, args, engineFlags []string, useEngine,
 	if !muteOutput {
 		cmd.Stderr = os.Stderr
 	}
-	cmd.Stdin = os.Stdin
+	// The prompts of a command whose output is captured can't be seen, it
+	// gets no input so it fails instead of waiting for an answer
+	if !captureOutput {
+		cmd.Stdin = os.Stdin
+	}
 
 	cmd.Env = append(os.Environ(), "DBX_SUDO_PROGRAM=pkexec")
 
@@ -115,6 +134,11 @@ func (d *DBox) RunCommand(command string, args, engineFlags []string, useEngine,
 			captureOutput, muteOutput, rootFull, detached)
 	}
 
+	if d.DryRun && !query {
+		printDryRun("%s %s", strings.Join(cmd.Env[len(os.Environ()):], " "), cmd.String())
+		return nil, nil
+	}
+
 	if detached {
 		return nil, cmd.Start()
 	}
@@ -122,7 +146,7 @@ func (d *DBox) RunCommand(command string, args, engineFlags []string, useEngine,
 	if captureOutput {
 		output, err := cmd.Output()
 		if err != nil {
-			if exitErr, ok := err.(*exec.ExitError); ok {
+			if exitErr, ok := err.(*exec.ExitError); ok && len(bytes.TrimSpace(exitErr.Stderr)) > 0 {
 				return output, errors.New(string(exitErr.Stderr))
 			}
 		}
@@ -133,48 +157,9 @@ func (d *DBox) RunCommand(command string, args, engineFlags []string, useEngine,
 }
 
 func (d *DBox) ListContainers(rootFull bool) ([]DBoxContainer, error) {
-	output, err := d.RunCommand("ps", []string{
-		"-a",
-		"--format", "{{.ID}}|{{.CreatedAt}}|{{.Status}}|{{.Labels}}|{{.Names}}",
-	}, nil, true, true, true, rootFull, false)
-	if err != nil {
-		return nil, err
-	}
-
-	var containers []DBoxContainer
-	for _, row := range strings.Split(string(output), "\n") {
-		if row == "" {
-			continue
-		}
-
-		parts := strings.Split(row, "|")
-		if len(parts) != 5 {
-			continue
-		}
-
-		container := DBoxContainer{
-			ID:        strings.Trim(parts[0], "\""),
-			CreatedAt: strings.Trim(parts[1], "\""),
-			Status:    strings.Trim(parts[2], "\""),
-			Name:      strings.Trim(parts[4], "\""),
-			Labels:    parseLabels(parts[3]),
-		}
-		containers = append(containers, container)
-	}
-
-	return containers, nil
-}
-
-func parseLabels(raw string) map[string]string {
-	labels := make(map[string]string)
-	raw = strings.TrimPrefix(strings.TrimSuffix(raw, "]"), "map[")
-	for _, item := range strings.Fields(raw) {
-		kv := strings.SplitN(item, ":", 2)
-		if len(kv) == 2 {
-			labels[kv[0]] = kv[1]
-		}
-	}
-	return labels
+	return listEngineContainers(d.Engine, func(args ...string) ([]byte, error) {
+		return d.runCommand(args[0], args[1:], nil, true, true, true, rootFull, false, true)
+	})
 }
 
 func (d *DBox) GetContainer(name string, rootFull bool) (*DBoxContainer, error) {
@@ -195,7 +180,16 @@ func (d *DBox) ContainerDelete(name string, rootFull bool) error {
 	return err
 }
 
-func (d *DBox) CreateContainer(name, image string, packages []string, home string, labels map[string]string, withInit, rootFull, unshared, withNvidia bool, hostname string) error {
+func (d *DBox) CreateContainer(name, image string, packages []string, home string, labels map[string]string, withInit, rootFull, unshared, withNvidia bool, hostname string, extras ContainerExtras) error {
+	// Distrobox always mounts the host root under /run/host, and sets the
+	// container up at its first start
+	if extras.IsolateHome {
+		return errors.New("the distrobox backend exposes the host filesystem, including the user home, under /run/host and can't isolate the home, use the podman backend")
+	}
+	if extras.ReadOnlyRoot {
+		return errors.New("the distrobox backend doesn't support a read-only root, use the podman backend")
+	}
+
 	args := []string{
 		"--image", image,
 		"--name", name,
@@ -215,6 +209,8 @@ func (d *DBox) CreateContainer(name, image string, packages []string, home strin
 	}
 	if unshared {
 		args = append(args, "--unshare-all")
+	} else if extras.Network == NetworkPrivate || extras.Network == NetworkNone {
+		args = append(args, "--unshare-netns")
 	}
 	if hostname != "" {
 		args = append(args, "--hostname", hostname)
@@ -222,17 +218,48 @@ func (d *DBox) CreateContainer(name, image string, packages []string, home strin
 	if len(packages) > 0 {
 		args = append(args, "--additional-packages", strings.Join(packages, " "))
 	}
+	for _, volume := range extras.Volumes {
+		args = append(args, "--volume", volume)
+	}
 
 	var engineFlags []string
 	for k, v := range labels {
-		engineFlags = append(engineFlags, fmt.Sprintf("--label=%s=%s", k, v))
+		engineFlags = append(engineFlags, shellQuote(fmt.Sprintf("--label=%s=%s", k, v)))
 	}
 	engineFlags = append(engineFlags, "--label=manager=abg")
+	for _, tmpfs := range extras.Tmpfs {
+		engineFlags = append(engineFlags, shellQuote("--tmpfs="+tmpfs))
+	}
+	for _, device := range extras.Devices {
+		engineFlags = append(engineFlags, shellQuote("--device="+device))
+	}
+	for _, env := range extras.Env {
+		engineFlags = append(engineFlags, shellQuote("--env="+env))
+	}
+	for _, capability := range extras.CapDrop {
+		engineFlags = append(engineFlags, shellQuote("--cap-drop="+capability))
+	}
+	if extras.Network == NetworkNone {
+		engineFlags = append(engineFlags, "--network=none")
+	}
+	for _, flag := range extras.EngineFlags {
+		engineFlags = append(engineFlags, shellQuote(flag))
+	}
 
 	_, err := d.RunCommand("create", args, engineFlags, false, false, false, rootFull, false)
 	return err
 }
 
+func (d *DBox) ContainerCommit(name, image string, rootFull bool) error {
+	_, err := d.RunCommand("commit", []string{name, image}, nil, true, false, true, rootFull, false)
+	return err
+}
+
+func (d *DBox) ImageDelete(image string, rootFull bool) error {
+	_, err := d.RunCommand("rmi", []string{"--force", image}, nil, true, false, true, rootFull, false)
+	return err
+}
+
 func (d *DBox) RunContainerCommand(name string, command []string, rootFull, detached bool) error {
 	args := append([]string{"--name", name, "--"}, command...)
 	_, err := d.RunCommand("run", args, nil, false, false, false, rootFull, detached)
@@ -248,6 +275,14 @@ func (d *DBox) ContainerExec(name string, captureOutput, muteOutput, rootFull, d
 	return string(out), err
 }
 
+// ContainerQuery runs a read-only command in a container and returns its
+// output. Unlike ContainerExec, it also runs in dry-run mode.
+func (d *DBox) ContainerQuery(name string, rootFull bool, args ...string) (string, error) {
+	fullArgs := append([]string{name, "--"}, args...)
+	out, err := d.runCommand("enter", fullArgs, nil, false, true, false, rootFull, false, true)
+	return string(out), err
+}
+
 func (d *DBox) ContainerEnter(name string, rootFull bool) error {
 	_, err := d.RunCommand("enter", []string{name}, nil, false, false, false, rootFull, false)
 	if err != nil && err.Error() == "exit status 130" {
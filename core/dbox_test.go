@@ -0,0 +1,99 @@
+package core
+
+import (
+	"maps"
+	"testing"
+)
+
+func TestParsePodmanContainers(t *testing.T) {
+	output := []byte(`[
+  {
+    "Id": "abc",
+    "Names": ["abg-dev"],
+    "Labels": {"name": "dev box", "stack": "my stack", "url": "https://example.org|x"},
+    "State": "running",
+    "Status": "Up 2 hours",
+    "CreatedAt": "2024-01-01"
+  },
+  {
+    "Id": "def",
+    "Names": ["plain"],
+    "Labels": null,
+    "State": "exited"
+  }
+]`)
+
+	containers, err := parsePodmanContainers(output)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(containers) != 2 {
+		t.Fatalf("expected 2 containers, got %d", len(containers))
+	}
+
+	c := containers[0]
+	if c.ID != "abc" || c.Name != "abg-dev" || c.Status != "Up 2 hours" || c.CreatedAt != "2024-01-01" {
+		t.Errorf("unexpected container: %+v", c)
+	}
+	want := map[string]string{"name": "dev box", "stack": "my stack", "url": "https://example.org|x"}
+	if !maps.Equal(c.Labels, want) {
+		t.Errorf("labels = %v, want %v", c.Labels, want)
+	}
+
+	if containers[1].Labels == nil || containers[1].Status != "exited" {
+		t.Errorf("unexpected container: %+v", containers[1])
+	}
+
+	containers, err = parsePodmanContainers([]byte("\n"))
+	if err != nil || len(containers) != 0 {
+		t.Errorf("empty output: %v, %v", containers, err)
+	}
+}
+
+func TestParseDockerContainers(t *testing.T) {
+	psOutput := []byte(`{"ID":"abc","Names":"abg-dev","Labels":"name=dev box,stack=a,b","State":"running","Status":"Up 2 hours","CreatedAt":"2024-01-01"}
+{"ID":"def","Names":"plain","Labels":"","State":"exited","Status":"Exited (0) 1 day ago","CreatedAt":"2024-01-02"}
+`)
+	inspectOutput := []byte(`[
+  {"Id": "abc", "Config": {"Labels": {"name": "dev box", "stack": "a,b"}}},
+  {"Id": "def", "Config": {"Labels": null}}
+]`)
+
+	containers, err := parseDockerContainers(psOutput)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = applyDockerLabels(containers, inspectOutput)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(containers) != 2 {
+		t.Fatalf("expected 2 containers, got %d", len(containers))
+	}
+	if containers[0].Name != "abg-dev" || containers[0].Status != "Up 2 hours" {
+		t.Errorf("unexpected container: %+v", containers[0])
+	}
+	want := map[string]string{"name": "dev box", "stack": "a,b"}
+	if !maps.Equal(containers[0].Labels, want) {
+		t.Errorf("labels = %v, want %v", containers[0].Labels, want)
+	}
+	if containers[1].Labels == nil || len(containers[1].Labels) != 0 {
+		t.Errorf("unexpected labels: %v", containers[1].Labels)
+	}
+}
+
+func TestShellQuote(t *testing.T) {
+	tests := map[string]string{
+		"--label=name=dev":        `'--label=name=dev'`,
+		"--label=name=dev box":    `'--label=name=dev box'`,
+		"--label=name=it's $HOME": `'--label=name=it'\''s $HOME'`,
+	}
+
+	for in, want := range tests {
+		if got := shellQuote(in); got != want {
+			t.Errorf("shellQuote(%q) = %s, want %s", in, got, want)
+		}
+	}
+}
@@ -0,0 +1,220 @@
+package core
+
+import (
+	"fmt"
+	"slices"
+)
+
+// StackDrift is the difference between the stack a subsystem was created
+// from and the current definition of that stack.
+type StackDrift struct {
+	StackDeleted        bool
+	OldBase             string
+	NewBase             string
+	OldPkgManager       string
+	NewPkgManager       string
+	AddedPackages       []string
+	RemovedPackages     []string
+	RepositoriesChanged bool
+	MountsChanged       bool
+	OldProfile          string
+	NewProfile          string
+	OldExtends          string
+	NewExtends          string
+	AddedPostCreate     []string // Post-create hooks run in place by Reconcile
+	PostCreateChanged   bool
+	PreRemoveChanged    bool
+}
+
+// HasDrift reports whether the stack changed since the subsystem creation.
+func (d *StackDrift) HasDrift() bool {
+	return d.StackDeleted || d.NeedsReset() || len(d.AddedPackages) > 0 || len(d.RemovedPackages) > 0 ||
+		d.OldExtends != d.NewExtends || d.PostCreateChanged || d.PreRemoveChanged
+}
+
+// NeedsReset reports whether the subsystem must be recreated to match its
+// stack. Packages and added post-create hooks can be reconciled in place,
+// the parent stack only matters through what it brings and pre-remove
+// hooks are read from the snapshot at removal.
+func (d *StackDrift) NeedsReset() bool {
+	return d.OldBase != d.NewBase || d.OldPkgManager != d.NewPkgManager || d.RepositoriesChanged || d.MountsChanged || d.OldProfile != d.NewProfile
+}
+
+// Details returns a line per difference, packages are prefixed with + when
+// added and - when removed.
+func (d *StackDrift) Details() []string {
+	details := make([]string, 0)
+
+	if d.StackDeleted {
+		return append(details, "stack deleted")
+	}
+	if d.OldBase != d.NewBase {
+		details = append(details, fmt.Sprintf("base: %s -> %s", d.OldBase, d.NewBase))
+	}
+	if d.OldPkgManager != d.NewPkgManager {
+		details = append(details, fmt.Sprintf("package manager: %s -> %s", d.OldPkgManager, d.NewPkgManager))
+	}
+	if d.RepositoriesChanged {
+		details = append(details, "repositories changed")
+	}
+	if d.MountsChanged {
+		details = append(details, "mounts changed")
+	}
+	if d.OldProfile != d.NewProfile {
+		details = append(details, fmt.Sprintf("security profile: %s -> %s", d.OldProfile, d.NewProfile))
+	}
+	if d.OldExtends != d.NewExtends {
+		details = append(details, fmt.Sprintf("extends: %s -> %s", d.OldExtends, d.NewExtends))
+	}
+	for _, hook := range d.AddedPostCreate {
+		details = append(details, "+ post-create: "+hook)
+	}
+	if d.PostCreateChanged && len(d.AddedPostCreate) == 0 {
+		details = append(details, "post-create hooks changed")
+	}
+	if d.PreRemoveChanged {
+		details = append(details, "pre-remove hooks changed")
+	}
+	for _, pkg := range d.AddedPackages {
+		details = append(details, "+ "+pkg)
+	}
+	for _, pkg := range d.RemovedPackages {
+		details = append(details, "- "+pkg)
+	}
+
+	return details
+}
+
+// StackDrift compares the stack snapshot of the subsystem with the current
+// definition of the stack.
+func (s *SubSystem) StackDrift() (*StackDrift, error) {
+	if !StackExists(s.Stack.Name) {
+		return &StackDrift{StackDeleted: true}, nil
+	}
+
+	current, err := LoadStack(s.Stack.Name)
+	if err != nil {
+		return nil, err
+	}
+
+	return compareStacks(s.Stack, current), nil
+}
+
+// compareStacks returns the drift from the old stack to the new one.
+func compareStacks(old, new *Stack) *StackDrift {
+	drift := &StackDrift{
+		OldBase:             old.Base,
+		NewBase:             new.Base,
+		OldPkgManager:       old.PkgManager,
+		NewPkgManager:       new.PkgManager,
+		AddedPackages:       make([]string, 0),
+		RemovedPackages:     make([]string, 0),
+		RepositoriesChanged: !slices.Equal(old.Repositories, new.Repositories),
+		MountsChanged:       !slices.Equal(old.Mounts, new.Mounts),
+		OldProfile:          old.Profile,
+		NewProfile:          new.Profile,
+		OldExtends:          old.Extends,
+		NewExtends:          new.Extends,
+		AddedPostCreate:     make([]string, 0),
+		PostCreateChanged:   !slices.Equal(old.PostCreate, new.PostCreate),
+		PreRemoveChanged:    !slices.Equal(old.PreRemove, new.PreRemove),
+	}
+
+	for _, hook := range new.PostCreate {
+		if !slices.Contains(old.PostCreate, hook) {
+			drift.AddedPostCreate = append(drift.AddedPostCreate, hook)
+		}
+	}
+
+	for _, pkg := range new.Packages {
+		if !slices.Contains(old.Packages, pkg) {
+			drift.AddedPackages = append(drift.AddedPackages, pkg)
+		}
+	}
+	for _, pkg := range old.Packages {
+		if !slices.Contains(new.Packages, pkg) {
+			drift.RemovedPackages = append(drift.RemovedPackages, pkg)
+		}
+	}
+
+	return drift
+}
+
+// Reconcile brings the subsystem in line with the current definition of its
+// stack and records the new snapshot. Package changes are applied in place:
+// added packages are installed and, with prune, dropped ones are removed
+// unless the user installed them. Without prune they are kept as if the
+// user installed them, so a reset restores them. Added post-create hooks
+// are run in place, after the packages. Other changes reset the subsystem,
+// as does any change to a subsystem with a sealed read-only root.
+func (s *SubSystem) Reconcile(prune bool) (*StackDrift, error) {
+	drift, err := s.StackDrift()
+	if err != nil {
+		return nil, err
+	}
+	if drift.StackDeleted {
+		return drift, fmt.Errorf("stack %s does not exist anymore", s.Stack.Name)
+	}
+	if !drift.HasDrift() {
+		return drift, nil
+	}
+
+	current, err := LoadStack(s.Stack.Name)
+	if err != nil {
+		return drift, err
+	}
+
+	tracking, err := s.LoadTracking()
+	if err != nil {
+		return drift, err
+	}
+
+	removed := slices.DeleteFunc(slices.Clone(drift.RemovedPackages), func(pkg string) bool {
+		return slices.Contains(tracking.Installed, pkg)
+	})
+	if !prune && len(removed) > 0 {
+		err = s.TrackInstalled(removed...)
+		if err != nil {
+			return drift, err
+		}
+	}
+
+	// A sealed root can't be changed in place
+	inPlace := len(drift.AddedPackages) > 0 || len(drift.AddedPostCreate) > 0 || (prune && len(removed) > 0)
+	if drift.NeedsReset() || (s.Image != "" && inPlace) {
+		s.Stack = current
+		return drift, s.Reset(false)
+	}
+
+	pkgManager, err := current.GetPkgManager()
+	if err != nil {
+		return drift, err
+	}
+
+	backend, err := NewBackend()
+	if err != nil {
+		return drift, err
+	}
+
+	if len(drift.AddedPackages) > 0 {
+		_, err = backend.ContainerExec(s.InternalName, false, false, s.IsRootfull, false, pkgManager.GenCmd(pkgManager.CmdInstall, drift.AddedPackages...)...)
+		if err != nil {
+			return drift, err
+		}
+	}
+
+	if prune && len(removed) > 0 {
+		_, err = backend.ContainerExec(s.InternalName, false, false, s.IsRootfull, false, pkgManager.GenCmd(pkgManager.CmdRemove, removed...)...)
+		if err != nil {
+			return drift, err
+		}
+	}
+
+	err = s.runHooks(backend, drift.AddedPostCreate)
+	if err != nil {
+		return drift, fmt.Errorf("post-create failed: %w", err)
+	}
+
+	s.Stack = current
+	return drift, s.saveRecord()
+}
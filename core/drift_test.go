@@ -0,0 +1,230 @@
+package core
+
+import (
+	"path/filepath"
+	"slices"
+	"testing"
+)
+
+func TestCompareStacks(t *testing.T) {
+	old := &Stack{Name: "s", Base: "ubuntu:22.04", PkgManager: "apt", Packages: []string{"htop", "git"}}
+
+	drift := compareStacks(old, &Stack{Name: "s", Base: "ubuntu:22.04", PkgManager: "apt", Packages: []string{"git", "htop"}})
+	if drift.HasDrift() {
+		t.Errorf("package order should not drift: %+v", drift)
+	}
+
+	drift = compareStacks(old, &Stack{Name: "s", Base: "ubuntu:22.04", PkgManager: "apt", Packages: []string{"git", "vim"}})
+	if !drift.HasDrift() || drift.NeedsReset() {
+		t.Errorf("package changes should drift without reset: %+v", drift)
+	}
+	if !slices.Equal(drift.AddedPackages, []string{"vim"}) || !slices.Equal(drift.RemovedPackages, []string{"htop"}) {
+		t.Errorf("added %v, removed %v", drift.AddedPackages, drift.RemovedPackages)
+	}
+
+	drift = compareStacks(old, &Stack{Name: "s", Base: "ubuntu:24.04", PkgManager: "apt", Packages: old.Packages})
+	if !drift.NeedsReset() || !slices.Equal(drift.Details(), []string{"base: ubuntu:22.04 -> ubuntu:24.04"}) {
+		t.Errorf("base change should need a reset: %v", drift.Details())
+	}
+
+	drift = compareStacks(old, &Stack{Name: "s", Base: "ubuntu:22.04", PkgManager: "apt", Packages: old.Packages, Repositories: []StackRepository{{Name: "r"}}})
+	if !drift.NeedsReset() {
+		t.Error("repository change should need a reset")
+	}
+
+	drift = compareStacks(old, &Stack{Name: "s", Extends: "base", Base: "ubuntu:22.04", PkgManager: "apt", Packages: old.Packages, PostCreate: []string{"setup"}, PreRemove: []string{"cleanup"}})
+	if !drift.HasDrift() || drift.NeedsReset() {
+		t.Errorf("hook and parent changes should drift without reset: %v", drift.Details())
+	}
+	want := []string{"extends:  -> base", "+ post-create: setup", "pre-remove hooks changed"}
+	if !slices.Equal(drift.Details(), want) {
+		t.Errorf("details = %v, want %v", drift.Details(), want)
+	}
+}
+
+func TestReconcileRunsAddedPostCreate(t *testing.T) {
+	fake := setupTestAbg(t)
+
+	writeTestPkgManager(t)
+	stackPath := filepath.Join(abg.Cnf.UserStacksPath, "ubuntu.yaml")
+	writeTestFile(t, stackPath, `
+name: ubuntu
+base: docker.io/library/ubuntu:22.04
+pkgmanager: apt
+postcreate: [echo one]
+`)
+
+	stack, err := LoadStack("ubuntu")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	subSystem := &SubSystem{InternalName: genInternalName("dev"), Name: "dev", Stack: stack}
+	err = subSystem.Create()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	writeTestFile(t, stackPath, `
+name: ubuntu
+base: docker.io/library/ubuntu:22.04
+pkgmanager: apt
+postcreate: [echo one, echo two]
+`)
+
+	_, err = subSystem.Reconcile(false)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var commands [][]string
+	for _, call := range fake.CallsTo("exec") {
+		commands = append(commands, call.Args)
+	}
+	want := [][]string{
+		{"sh", "-c", "echo one"},
+		{"sh", "-c", "echo two"},
+	}
+	if !slices.EqualFunc(commands, want, slices.Equal[[]string]) {
+		t.Errorf("exec calls = %v, want %v", commands, want)
+	}
+
+	drift, err := subSystem.StackDrift()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if drift.HasDrift() {
+		t.Errorf("subsystem should be reconciled: %v", drift.Details())
+	}
+}
+
+func TestReconcilePackages(t *testing.T) {
+	fake := setupTestAbg(t)
+
+	writeTestPkgManager(t)
+	stackPath := filepath.Join(abg.Cnf.UserStacksPath, "ubuntu.yaml")
+	writeTestFile(t, stackPath, `
+name: ubuntu
+base: docker.io/library/ubuntu:22.04
+pkgmanager: apt
+packages: [htop, git, curl]
+`)
+
+	stack, err := LoadStack("ubuntu")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	subSystem := &SubSystem{InternalName: genInternalName("dev"), Name: "dev", Stack: stack}
+	err = subSystem.Create()
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = subSystem.TrackInstalled("curl")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	writeTestFile(t, stackPath, `
+name: ubuntu
+base: docker.io/library/ubuntu:22.04
+pkgmanager: apt
+packages: [git, vim]
+`)
+
+	loaded, err := LoadSubSystem("dev", false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !slices.Equal(loaded.Stack.Packages, []string{"htop", "git", "curl"}) {
+		t.Errorf("subsystem should keep its stack snapshot, got %v", loaded.Stack.Packages)
+	}
+
+	_, err = loaded.Reconcile(true)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var commands [][]string
+	for _, call := range fake.CallsTo("exec") {
+		commands = append(commands, call.Args)
+	}
+	want := [][]string{
+		{"sudo", "apt", "install", "-y", "vim"},
+		{"sudo", "apt", "remove", "-y", "htop"},
+	}
+	if !slices.EqualFunc(commands, want, slices.Equal[[]string]) {
+		t.Errorf("exec calls = %v, want %v", commands, want)
+	}
+	if len(fake.CallsTo("delete")) != 0 {
+		t.Error("package drift should not reset the subsystem")
+	}
+
+	loaded, err = LoadSubSystem("dev", false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	drift, err := loaded.StackDrift()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if drift.HasDrift() {
+		t.Errorf("subsystem should be reconciled: %v", drift.Details())
+	}
+}
+
+func TestReconcileKeepsDroppedPackages(t *testing.T) {
+	fake := setupTestAbg(t)
+
+	writeTestPkgManager(t)
+	stackPath := filepath.Join(abg.Cnf.UserStacksPath, "ubuntu.yaml")
+	writeTestFile(t, stackPath, `
+name: ubuntu
+base: docker.io/library/ubuntu:22.04
+pkgmanager: apt
+packages: [htop, git]
+`)
+
+	stack, err := LoadStack("ubuntu")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	subSystem := &SubSystem{InternalName: genInternalName("dev"), Name: "dev", Stack: stack}
+	err = subSystem.Create()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	writeTestFile(t, stackPath, `
+name: ubuntu
+base: docker.io/library/ubuntu:24.04
+pkgmanager: apt
+packages: [git]
+`)
+
+	_, err = subSystem.Reconcile(false)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(fake.CallsTo("delete")) != 1 || len(fake.CallsTo("create")) != 2 {
+		t.Errorf("base change should recreate the subsystem: %+v", fake.Calls)
+	}
+
+	tracking, err := subSystem.LoadTracking()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !slices.Equal(tracking.Installed, []string{"htop"}) {
+		t.Errorf("dropped package should be kept, tracked %v", tracking.Installed)
+	}
+
+	var installed bool
+	for _, call := range fake.CallsTo("exec") {
+		installed = installed || slices.Equal(call.Args, []string{"sudo", "apt", "install", "-y", "htop"})
+	}
+	if !installed {
+		t.Errorf("dropped package should be reinstalled after the reset: %+v", fake.CallsTo("exec"))
+	}
+}
@@ -0,0 +1,26 @@
+package core
+
+import (
+	"fmt"
+	"os"
+)
+
+// dryRun is set by SetDryRun, ABG_DRY_RUN=1 has the same effect.
+var dryRun bool
+
+// SetDryRun enables or disables dry-run mode. In dry-run mode, commands
+// changing containers are printed instead of being executed and nothing is
+// written to the host, while read-only queries still run.
+func SetDryRun(enabled bool) {
+	dryRun = enabled
+}
+
+// IsDryRun informs whether dry-run mode is enabled.
+func IsDryRun() bool {
+	return dryRun || os.Getenv("ABG_DRY_RUN") == "1"
+}
+
+// printDryRun prints an action skipped because of dry-run mode.
+func printDryRun(format string, args ...interface{}) {
+	fmt.Printf("[dry-run] "+format+"\n", args...)
+}
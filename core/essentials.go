@@ -28,6 +28,10 @@ Please refer to our documentation at https://documentation.auruos.org/`)
 		return err
 	}
 
+	if err := a.EnsureDirectory(a.Cnf.UserRecipesPath, "user recipes"); err != nil {
+		return err
+	}
+
 	return nil
 }
 
@@ -0,0 +1,487 @@
+package core
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"regexp"
+	"slices"
+	"strings"
+	"syscall"
+	"time"
+)
+
+// Export types stored in the export registry.
+const (
+	ExportTypeApp = "app"
+	ExportTypeBin = "bin"
+)
+
+// ExportEntry is a desktop entry or binary exported from a subsystem, with
+// the files its export created on the host.
+type ExportEntry struct {
+	Type       string
+	Name       string // Application name or binary path inside the subsystem
+	ExportPath string // Only set for binaries
+	HostFiles  []string
+	ExportedAt time.Time
+}
+
+// exportRegistryPath returns the path of the export registry, which maps
+// subsystem storage names, see storageName, to their exports.
+func exportRegistryPath() string {
+	return filepath.Join(abg.Cnf.AbgStoragePath, "exports.json")
+}
+
+// loadExportRegistry loads the export registry, an empty one is returned if
+// nothing was exported yet.
+func loadExportRegistry() (map[string][]*ExportEntry, error) {
+	registry := map[string][]*ExportEntry{}
+
+	data, err := os.ReadFile(exportRegistryPath())
+	if err != nil {
+		if os.IsNotExist(err) {
+			return registry, nil
+		}
+		return nil, err
+	}
+
+	err = json.Unmarshal(data, &registry)
+	if err != nil {
+		return nil, err
+	}
+
+	return registry, nil
+}
+
+// saveExportRegistry writes the export registry. It is written to a
+// temporary file first and renamed, so it is never read half written.
+func saveExportRegistry(registry map[string][]*ExportEntry) error {
+	data, err := json.MarshalIndent(registry, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	tmp, err := os.CreateTemp(filepath.Dir(exportRegistryPath()), "exports-*.json")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+
+	_, err = tmp.Write(data)
+	if closeErr := tmp.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		return err
+	}
+
+	err = os.Chmod(tmp.Name(), 0644)
+	if err != nil {
+		return err
+	}
+
+	return os.Rename(tmp.Name(), exportRegistryPath())
+}
+
+// updateExportRegistry loads, changes and saves the export registry while
+// holding a lock on it, so concurrent abg processes don't lose each other's
+// changes.
+func updateExportRegistry(update func(registry map[string][]*ExportEntry)) error {
+	if IsDryRun() {
+		return nil
+	}
+
+	err := os.MkdirAll(filepath.Dir(exportRegistryPath()), 0755)
+	if err != nil {
+		return err
+	}
+
+	lock, err := os.OpenFile(exportRegistryPath()+".lock", os.O_CREATE|os.O_RDWR, 0644)
+	if err != nil {
+		return err
+	}
+	defer lock.Close()
+
+	err = syscall.Flock(int(lock.Fd()), syscall.LOCK_EX)
+	if err != nil {
+		return err
+	}
+	defer syscall.Flock(int(lock.Fd()), syscall.LOCK_UN)
+
+	registry, err := loadExportRegistry()
+	if err != nil {
+		return err
+	}
+
+	update(registry)
+
+	return saveExportRegistry(registry)
+}
+
+// ListAllExports returns the exports of every subsystem, keyed by subsystem
+// storage name.
+func ListAllExports() (map[string][]*ExportEntry, error) {
+	return loadExportRegistry()
+}
+
+// LoadExports returns the desktop entries and binaries exported from the
+// subsystem.
+func (s *SubSystem) LoadExports() ([]*ExportEntry, error) {
+	return loadExports(storageName(s.InternalName, s.IsRootfull))
+}
+
+// exportedPrograms returns the applications and binaries of the given
+// exports keyed by name, with the host file launching them. Applications
+// take precedence over binaries of the same name.
+func exportedPrograms(entries []*ExportEntry) map[string]map[string]string {
+	programs := map[string]map[string]string{}
+
+	for _, exportType := range []string{ExportTypeBin, ExportTypeApp} {
+		for _, entry := range entries {
+			if entry.Type != exportType {
+				continue
+			}
+
+			name := entry.Name
+			if entry.Type == ExportTypeBin {
+				name = filepath.Base(name)
+			}
+
+			launcher := ""
+			if len(entry.HostFiles) > 0 {
+				launcher = entry.HostFiles[0]
+			}
+			programs[name] = map[string]string{"Name": name, "Exec": launcher}
+		}
+	}
+
+	return programs
+}
+
+// loadExports returns the exports of the container stored under name.
+func loadExports(name string) ([]*ExportEntry, error) {
+	registry, err := loadExportRegistry()
+	if err != nil {
+		return nil, err
+	}
+
+	return registry[name], nil
+}
+
+// registerExport adds an export of the container stored under name to the
+// registry, replacing a previous export of the same application or binary.
+func registerExport(name string, entry *ExportEntry) error {
+	return updateExportRegistry(func(registry map[string][]*ExportEntry) {
+		entries := slices.DeleteFunc(registry[name], func(e *ExportEntry) bool {
+			return e.Type == entry.Type && e.Name == entry.Name
+		})
+		registry[name] = append(entries, entry)
+	})
+}
+
+// unregisterExport removes an export of the container stored under name
+// from the registry and deletes the host files recorded for it. The
+// backends only remove the launchers they write to the default
+// directories, not the <bin>-<internalName> copies nor the binaries
+// exported to a custom path.
+func unregisterExport(name, exportType, exportName string) error {
+	entries, err := loadExports(name)
+	if err != nil {
+		return err
+	}
+
+	for _, entry := range entries {
+		if entry.Type == exportType && entry.Name == exportName {
+			_, err = removeHostFiles(entry.HostFiles)
+			if err != nil {
+				return err
+			}
+		}
+	}
+
+	return updateExportRegistry(func(registry map[string][]*ExportEntry) {
+		entries := slices.DeleteFunc(registry[name], func(e *ExportEntry) bool {
+			return e.Type == exportType && e.Name == exportName
+		})
+		if len(entries) == 0 {
+			delete(registry, name)
+		} else {
+			registry[name] = entries
+		}
+	})
+}
+
+// forgetExports removes every export of the container stored under name
+// from the registry.
+func forgetExports(name string) error {
+	return updateExportRegistry(func(registry map[string][]*ExportEntry) {
+		delete(registry, name)
+	})
+}
+
+// RemoveExportedFiles deletes the host files of every registered export of
+// the subsystem, returning the removed paths.
+func (s *SubSystem) RemoveExportedFiles() ([]string, error) {
+	return removeExportedFiles(storageName(s.InternalName, s.IsRootfull))
+}
+
+// removeExportedFiles deletes the host files of every registered export of
+// the container stored under name and forgets them, returning the removed
+// paths.
+func removeExportedFiles(name string) ([]string, error) {
+	entries, err := loadExports(name)
+	if err != nil {
+		return nil, err
+	}
+
+	files := make([]string, 0)
+	for _, entry := range entries {
+		files = append(files, entry.HostFiles...)
+	}
+
+	removed, err := removeHostFiles(files)
+	if err != nil {
+		return removed, err
+	}
+
+	return removed, forgetExports(name)
+}
+
+// RemoveHostArtefacts deletes every file on the host belonging to the
+// subsystem: registered exports, plus desktop entries and binary wrappers
+// pointing at its container found by scanning the export directories. It is
+// meant to clean up launchers left behind once the container is gone.
+func (s *SubSystem) RemoveHostArtefacts() ([]string, error) {
+	entries, err := s.LoadExports()
+	if err != nil {
+		return nil, err
+	}
+
+	files := make([]string, 0)
+	binDirs := make([]string, 0)
+	for _, entry := range entries {
+		files = append(files, entry.HostFiles...)
+		if entry.Type == ExportTypeBin && entry.ExportPath != "" {
+			binDirs = append(binDirs, entry.ExportPath)
+		}
+	}
+
+	for _, file := range findHostArtefacts(s.InternalName, binDirs) {
+		if !slices.Contains(files, file) {
+			files = append(files, file)
+		}
+	}
+
+	removed, err := removeHostFiles(files)
+	if err != nil {
+		return removed, err
+	}
+
+	return removed, forgetExports(storageName(s.InternalName, s.IsRootfull))
+}
+
+// removeHostFiles deletes the given files, skipping the missing ones, and
+// returns the removed paths.
+func removeHostFiles(files []string) ([]string, error) {
+	removed := make([]string, 0)
+	for _, file := range files {
+		if IsDryRun() {
+			if _, err := os.Stat(file); err == nil {
+				printDryRun("rm %s", file)
+				removed = append(removed, file)
+			}
+			continue
+		}
+
+		err := os.Remove(file)
+		if err != nil {
+			if os.IsNotExist(err) {
+				continue
+			}
+			return removed, err
+		}
+		removed = append(removed, file)
+	}
+
+	return removed, nil
+}
+
+// restoreExports exports again the given entries, used after the subsystem
+// container is recreated.
+func (s *SubSystem) restoreExports(entries []*ExportEntry) error {
+	for _, entry := range entries {
+		var err error
+		switch entry.Type {
+		case ExportTypeApp:
+			err = s.ExportDesktopEntry(entry.Name)
+		case ExportTypeBin:
+			err = s.ExportBin(entry.Name, entry.ExportPath)
+		}
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// hostApplicationsDir returns the directory distrobox-export writes desktop
+// entries to.
+func hostApplicationsDir() (string, error) {
+	userHome, err := os.UserHomeDir()
+	if err != nil {
+		return "", err
+	}
+
+	return filepath.Join(userHome, ".local", "share", "applications"), nil
+}
+
+// snapshotDir returns the modification time of every file of a directory.
+func snapshotDir(dir string) map[string]time.Time {
+	snapshot := map[string]time.Time{}
+
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return snapshot
+	}
+
+	for _, entry := range entries {
+		info, err := entry.Info()
+		if err != nil || info.IsDir() {
+			continue
+		}
+		snapshot[filepath.Join(dir, entry.Name())] = info.ModTime()
+	}
+
+	return snapshot
+}
+
+// changedFiles returns the files of a directory created or modified since
+// the given snapshot was taken.
+func changedFiles(dir string, before map[string]time.Time) []string {
+	files := make([]string, 0)
+	for file, modTime := range snapshotDir(dir) {
+		if previous, ok := before[file]; !ok || !previous.Equal(modTime) {
+			files = append(files, file)
+		}
+	}
+	slices.Sort(files)
+
+	return files
+}
+
+// exportedDesktopFiles returns the host files recorded for an exported
+// application of the container stored under name.
+func exportedDesktopFiles(name, app string) ([]string, error) {
+	registry, err := loadExportRegistry()
+	if err != nil {
+		return nil, err
+	}
+
+	for _, entry := range registry[name] {
+		if entry.Type == ExportTypeApp && entry.Name == app {
+			return entry.HostFiles, nil
+		}
+	}
+
+	return nil, nil
+}
+
+// hostBinDir returns the default directory binaries are exported to.
+func hostBinDir() (string, error) {
+	userHome, err := os.UserHomeDir()
+	if err != nil {
+		return "", err
+	}
+
+	return filepath.Join(userHome, ".local", "bin"), nil
+}
+
+// maxArtefactSize is the size above which a file can't be a distrobox
+// launcher, so it is not read while scanning.
+const maxArtefactSize = 64 * 1024
+
+// findHostArtefacts scans the host applications directory, the default bin
+// directory and the given extra bin directories for files launching the
+// container: desktop entries whose Exec enters it, binary launchers naming
+// it and the <bin>-<internalName> copies ExportBin creates when a
+// binary with the same name already exists. Files are matched on the exact
+// container name, never on a name prefix, which other subsystems may share.
+func findHostArtefacts(internalName string, extraBinDirs []string) []string {
+	artefacts := make([]string, 0)
+
+	// distrobox-enter -n <name> for distrobox, <engine> exec <name> for podman
+	enterRe := regexp.MustCompile(`((-n|--name)|\bexec(\s+--?[a-z-]+)*)\s+` + regexp.QuoteMeta(internalName) + `(\s|$)`)
+	nameRe := regexp.MustCompile(`(?m)^# name: ` + regexp.QuoteMeta(internalName) + `\s*$`)
+
+	if appsDir, err := hostApplicationsDir(); err == nil {
+		for _, file := range scanArtefactDir(appsDir) {
+			if filepath.Ext(file) != ".desktop" {
+				continue
+			}
+
+			content, err := os.ReadFile(file)
+			if err != nil {
+				continue
+			}
+
+			if enterRe.Match(content) {
+				artefacts = append(artefacts, file)
+			}
+		}
+	}
+
+	binDirs := extraBinDirs
+	if binDir, err := hostBinDir(); err == nil {
+		binDirs = append([]string{binDir}, binDirs...)
+	}
+
+	for _, binDir := range binDirs {
+		for _, file := range scanArtefactDir(binDir) {
+			if slices.Contains(artefacts, file) {
+				continue
+			}
+
+			content, err := os.ReadFile(file)
+			if err != nil {
+				continue
+			}
+
+			isLauncher := strings.Contains(string(content), "# distrobox_binary") || strings.Contains(string(content), "# abg_binary")
+			isWrapper := isLauncher && nameRe.Match(content)
+			isCopy := strings.HasSuffix(filepath.Base(file), "-"+internalName) && enterRe.Match(content)
+			if isWrapper || isCopy {
+				artefacts = append(artefacts, file)
+			}
+		}
+	}
+
+	return artefacts
+}
+
+// scanArtefactDir returns the regular files of a directory small enough to
+// be a launcher.
+func scanArtefactDir(dir string) []string {
+	files := make([]string, 0)
+
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return files
+	}
+
+	for _, entry := range entries {
+		if !entry.Type().IsRegular() {
+			continue
+		}
+
+		info, err := entry.Info()
+		if err != nil || info.Size() > maxArtefactSize {
+			continue
+		}
+
+		files = append(files, filepath.Join(dir, entry.Name()))
+	}
+
+	return files
+}
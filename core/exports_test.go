@@ -0,0 +1,236 @@
+package core
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"sync"
+	"testing"
+)
+
+func TestRemoveHostArtefactsSharedPrefix(t *testing.T) {
+	setupTestAbg(t)
+
+	appsDir, err := hostApplicationsDir()
+	if err != nil {
+		t.Fatal(err)
+	}
+	binDir, err := hostBinDir()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, name := range []string{"abg-foo", "abg-foo-bar"} {
+		writeTestFile(t, filepath.Join(appsDir, name+"-gedit.desktop"), "[Desktop Entry]\nExec=/usr/bin/distrobox-enter  -n "+name+"  --   gedit %U\n")
+		writeTestFile(t, filepath.Join(appsDir, name+"-btop.desktop"), "[Desktop Entry]\nExec=/usr/bin/podman exec "+name+" btop\n")
+		writeTestFile(t, filepath.Join(binDir, "rg-"+name), "#!/bin/sh\n# distrobox_binary\n# name: "+name+"\nexec /usr/bin/distrobox-enter -n "+name+" -- /usr/bin/rg \"$@\"\n")
+		writeTestFile(t, filepath.Join(binDir, "fd-"+name), "#!/bin/sh\n# abg_binary\n# name: "+name+"\nexec /usr/bin/podman exec --interactive "+name+" /usr/bin/fd \"$@\"\n")
+	}
+
+	subSystem := &SubSystem{InternalName: "abg-foo", Name: "foo", Stack: &Stack{Name: "ubuntu"}}
+	removed, err := subSystem.RemoveHostArtefacts()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := []string{
+		filepath.Join(appsDir, "abg-foo-btop.desktop"),
+		filepath.Join(appsDir, "abg-foo-gedit.desktop"),
+		filepath.Join(binDir, "fd-abg-foo"),
+		filepath.Join(binDir, "rg-abg-foo"),
+	}
+	slices.Sort(removed)
+	slices.Sort(want)
+	if !slices.Equal(removed, want) {
+		t.Errorf("removed %v, want %v", removed, want)
+	}
+
+	for _, file := range []string{"abg-foo-bar-gedit.desktop", "abg-foo-bar-btop.desktop"} {
+		if _, err := os.Stat(filepath.Join(appsDir, file)); err != nil {
+			t.Errorf("%s of the other subsystem was removed", file)
+		}
+	}
+	for _, file := range []string{"rg-abg-foo-bar", "fd-abg-foo-bar"} {
+		if _, err := os.Stat(filepath.Join(binDir, file)); err != nil {
+			t.Errorf("%s of the other subsystem was removed", file)
+		}
+	}
+}
+
+func TestExportDesktopEntryRecordsWrittenFiles(t *testing.T) {
+	fake := setupTestAbg(t)
+
+	appsDir, err := hostApplicationsDir()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// A subsystem sharing the name prefix already exported the same app
+	other := filepath.Join(appsDir, "abg-dev-bar-org.gnome.TextEditor.desktop")
+	writeTestFile(t, other, "[Desktop Entry]\nExec=/usr/bin/distrobox-enter -n abg-dev-bar -- gnome-text-editor\n")
+
+	written := filepath.Join(appsDir, "abg-dev-org.gnome.TextEditor.desktop")
+	fake.HostFiles["gnome-text-editor on dev"] = map[string]string{
+		written: "[Desktop Entry]\nExec=/usr/bin/distrobox-enter -n abg-dev -- gnome-text-editor\n",
+	}
+
+	subSystem := &SubSystem{InternalName: "abg-dev", Name: "dev", Stack: &Stack{Name: "ubuntu"}}
+	err = subSystem.ExportDesktopEntry("gnome-text-editor")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	exports, err := subSystem.LoadExports()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(exports) != 1 || !slices.Equal(exports[0].HostFiles, []string{written}) {
+		t.Errorf("unexpected exports: %+v", exports)
+	}
+}
+
+func TestUnexportBinRemovesRecordedFiles(t *testing.T) {
+	setupTestAbg(t)
+
+	binDir, err := hostBinDir()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Another subsystem owns rg, so the export is the collision copy
+	owned := filepath.Join(binDir, "rg")
+	writeTestFile(t, owned, "#!/bin/sh\n# distrobox_binary\n# name: abg-other\n")
+	copied := filepath.Join(binDir, "rg-abg-dev")
+	custom := filepath.Join(t.TempDir(), "fd")
+
+	subSystem := &SubSystem{InternalName: "abg-dev", Name: "dev", Stack: &Stack{Name: "ubuntu"}}
+	for _, entry := range []*ExportEntry{
+		{Type: ExportTypeBin, Name: "/usr/bin/rg", ExportPath: binDir, HostFiles: []string{copied}},
+		{Type: ExportTypeBin, Name: "/usr/bin/fd", ExportPath: filepath.Dir(custom), HostFiles: []string{custom}},
+	} {
+		writeTestFile(t, entry.HostFiles[0], "#!/bin/sh\n")
+		err = registerExport(subSystem.InternalName, entry)
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	for _, binary := range []string{"/usr/bin/rg", "/usr/bin/fd"} {
+		err = subSystem.UnexportBin(binary, "")
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	for _, file := range []string{copied, custom} {
+		if _, err := os.Stat(file); !os.IsNotExist(err) {
+			t.Errorf("%s was not removed", file)
+		}
+	}
+	if _, err := os.Stat(owned); err != nil {
+		t.Error("the binary of the other subsystem was removed")
+	}
+
+	exports, err := subSystem.LoadExports()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(exports) != 0 {
+		t.Errorf("the exports were not unregistered: %+v", exports)
+	}
+}
+
+func TestRegisterExportConcurrently(t *testing.T) {
+	setupTestAbg(t)
+
+	var wg sync.WaitGroup
+	for i := 0; i < 20; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+
+			err := registerExport("abg-dev", &ExportEntry{Type: ExportTypeApp, Name: fmt.Sprintf("app-%d", i)})
+			if err != nil {
+				t.Error(err)
+			}
+		}(i)
+	}
+	wg.Wait()
+
+	exports, err := loadExports("abg-dev")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(exports) != 20 {
+		t.Errorf("%d exports registered, want 20", len(exports))
+	}
+}
+
+func TestListSubSystemsExportedPrograms(t *testing.T) {
+	fake := setupTestAbg(t)
+	addTestContainers(fake, "dev")
+
+	binDir, err := hostBinDir()
+	if err != nil {
+		t.Fatal(err)
+	}
+	appsDir, err := hostApplicationsDir()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, entry := range []*ExportEntry{
+		{Type: ExportTypeApp, Name: "htop", HostFiles: []string{filepath.Join(appsDir, "abg-dev-htop.desktop")}},
+		{Type: ExportTypeBin, Name: "/usr/bin/rg", HostFiles: []string{filepath.Join(binDir, "rg")}},
+	} {
+		err = registerExport("abg-dev", entry)
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	// A launcher the registry doesn't know is not an export of the subsystem
+	writeTestFile(t, filepath.Join(binDir, "fd"), "#!/bin/sh\n# distrobox_binary\n# name: abg-dev\n")
+
+	subSystems, err := ListSubSystems(false, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(subSystems) != 1 {
+		t.Fatalf("expected one subsystem, got %d", len(subSystems))
+	}
+
+	want := map[string]map[string]string{
+		"htop": {"Name": "htop", "Exec": filepath.Join(appsDir, "abg-dev-htop.desktop")},
+		"rg":   {"Name": "rg", "Exec": filepath.Join(binDir, "rg")},
+	}
+	if got := subSystems[0].ExportedPrograms; fmt.Sprint(got) != fmt.Sprint(want) {
+		t.Errorf("exported programs = %v, want %v", got, want)
+	}
+}
+
+func TestRewriteDesktopEntryStartsContainer(t *testing.T) {
+	setupTestAbg(t)
+
+	p := &PodmanBackend{EngineBinary: "/usr/bin/podman"}
+	content := "[Desktop Entry]\nName=Gedit\nTryExec=gedit\nExec=gedit %U\n"
+
+	got := p.rewriteDesktopEntry(content, "abg-dev", "dev", false)
+	want := "[Desktop Entry]\nName=Gedit (dev)\n" +
+		`Exec=sh -c "/usr/bin/podman start abg-dev >/dev/null 2>&1; exec \\"\\$0\\" \\"\\$@\\"" /usr/bin/podman exec abg-dev gedit %U` + "\n"
+	if got != want {
+		t.Errorf("desktop entry = %q, want %q", got, want)
+	}
+
+	appsDir, err := hostApplicationsDir()
+	if err != nil {
+		t.Fatal(err)
+	}
+	file := filepath.Join(appsDir, "abg-dev-gedit.desktop")
+	writeTestFile(t, file, got)
+
+	artefacts := findHostArtefacts("abg-dev", nil)
+	if !slices.Equal(artefacts, []string{file}) {
+		t.Errorf("artefacts = %v, want %v", artefacts, []string{file})
+	}
+}
@@ -0,0 +1,255 @@
+package core
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+)
+
+var _ Backend = (*FakeBackend)(nil)
+
+// FakeCall is a backend call recorded by FakeBackend.
+type FakeCall struct {
+	Method   string
+	Name     string
+	RootFull bool
+	Args     []string
+	Labels   map[string]string // Only set for create
+	Home     string            // Only set for create
+	Extras   ContainerExtras   // Only set for create
+	Captured bool              // Only set for exec, whether the output is captured
+}
+
+// FakeBackend is an in-memory Backend for tests. It records every call,
+// serves its containers through the same ps output format the engines
+// produce and answers commands with canned outputs.
+type FakeBackend struct {
+	// Containers are the rootless (false) and rootful (true) containers,
+	// created and deleted containers are added and removed.
+	Containers map[bool][]DBoxContainer
+	// Outputs maps a command, its arguments joined by spaces, to its output.
+	Outputs map[string]string
+	// Errors maps a command, its arguments joined by spaces, to its error.
+	Errors map[string]error
+	// HostFiles maps a command, its arguments joined by spaces, to the files
+	// it writes on the host, by path, as exports do.
+	HostFiles map[string]map[string]string
+	// NoCreatePackages makes InstallsPackages return false, like the podman
+	// backend.
+	NoCreatePackages bool
+	// OnRun, if set, is called with each command before it returns, e.g. to
+	// slow it down.
+	OnRun func(call FakeCall)
+
+	mu    sync.Mutex
+	Calls []FakeCall
+}
+
+// NewFakeBackend returns an empty FakeBackend.
+func NewFakeBackend() *FakeBackend {
+	return &FakeBackend{
+		Containers: map[bool][]DBoxContainer{},
+		Outputs:    map[string]string{},
+		Errors:     map[string]error{},
+		HostFiles:  map[string]map[string]string{},
+	}
+}
+
+// CallsTo returns the recorded calls of a method, in order.
+func (f *FakeBackend) CallsTo(method string) []FakeCall {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	calls := make([]FakeCall, 0)
+	for _, call := range f.Calls {
+		if call.Method == method {
+			calls = append(calls, call)
+		}
+	}
+
+	return calls
+}
+
+func (f *FakeBackend) record(call FakeCall) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	f.Calls = append(f.Calls, call)
+}
+
+// run records a command and returns its canned output and error.
+func (f *FakeBackend) run(method, name string, rootFull bool, args []string) (string, error) {
+	return f.runCall(FakeCall{Method: method, Name: name, RootFull: rootFull, Args: args})
+}
+
+func (f *FakeBackend) runCall(call FakeCall) (string, error) {
+	f.record(call)
+	if f.OnRun != nil {
+		f.OnRun(call)
+	}
+
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	command := strings.Join(call.Args, " ")
+	for path, content := range f.HostFiles[command] {
+		err := os.MkdirAll(filepath.Dir(path), 0755)
+		if err == nil {
+			err = os.WriteFile(path, []byte(content), 0644)
+		}
+		if err != nil {
+			return "", err
+		}
+	}
+
+	return f.Outputs[command], f.Errors[command]
+}
+
+func (f *FakeBackend) InstallsPackages() bool {
+	return !f.NoCreatePackages
+}
+
+func (f *FakeBackend) CreateContainer(name, image string, packages []string, home string, labels map[string]string, withInit, rootFull, unshared, withNvidia bool, hostname string, extras ContainerExtras) error {
+	f.record(FakeCall{Method: "create", Name: name, RootFull: rootFull, Args: packages, Labels: labels, Home: home, Extras: extras})
+
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if err := f.Errors["create "+name]; err != nil {
+		return err
+	}
+
+	containerLabels := map[string]string{"manager": "abg"}
+	for k, v := range labels {
+		containerLabels[k] = v
+	}
+
+	f.Containers[rootFull] = append(f.Containers[rootFull], DBoxContainer{
+		ID:        fmt.Sprintf("%012d", len(f.Calls)),
+		CreatedAt: "now",
+		Status:    "Created",
+		Labels:    containerLabels,
+		Name:      name,
+	})
+
+	return nil
+}
+
+// ListContainers renders the containers as podman ps --format json does and
+// parses them back, as the real backends do.
+func (f *FakeBackend) ListContainers(rootFull bool) ([]DBoxContainer, error) {
+	f.record(FakeCall{Method: "list", RootFull: rootFull})
+
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	output, err := formatContainerList(f.Containers[rootFull])
+	if err != nil {
+		return nil, err
+	}
+
+	return parsePodmanContainers(output)
+}
+
+func (f *FakeBackend) GetContainer(name string, rootFull bool) (*DBoxContainer, error) {
+	containers, err := f.ListContainers(rootFull)
+	if err != nil {
+		return nil, err
+	}
+	for _, c := range containers {
+		if c.Name == name {
+			return &c, nil
+		}
+	}
+	return nil, errors.New("container not found")
+}
+
+func (f *FakeBackend) ContainerExec(name string, captureOutput, muteOutput, rootFull, detached bool, args ...string) (string, error) {
+	return f.runCall(FakeCall{Method: "exec", Name: name, RootFull: rootFull, Args: args, Captured: captureOutput})
+}
+
+func (f *FakeBackend) ContainerQuery(name string, rootFull bool, args ...string) (string, error) {
+	return f.run("query", name, rootFull, args)
+}
+
+func (f *FakeBackend) ContainerEnter(name string, rootFull bool) error {
+	_, err := f.run("enter", name, rootFull, nil)
+	return err
+}
+
+func (f *FakeBackend) ContainerStart(name string, rootFull bool) error {
+	_, err := f.run("start", name, rootFull, nil)
+	return err
+}
+
+func (f *FakeBackend) ContainerStop(name string, rootFull bool) error {
+	_, err := f.run("stop", name, rootFull, nil)
+	return err
+}
+
+func (f *FakeBackend) ContainerDelete(name string, rootFull bool) error {
+	f.record(FakeCall{Method: "delete", Name: name, RootFull: rootFull})
+
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	containers := make([]DBoxContainer, 0)
+	for _, c := range f.Containers[rootFull] {
+		if c.Name != name {
+			containers = append(containers, c)
+		}
+	}
+	f.Containers[rootFull] = containers
+
+	return nil
+}
+
+func (f *FakeBackend) ContainerCommit(name, image string, rootFull bool) error {
+	_, err := f.run("commit", name, rootFull, []string{image})
+	return err
+}
+
+func (f *FakeBackend) ImageDelete(image string, rootFull bool) error {
+	_, err := f.run("rmi", "", rootFull, []string{image})
+	return err
+}
+
+func (f *FakeBackend) ContainerExportDesktopEntry(name, app, label string, rootFull bool) error {
+	_, err := f.run("exportApp", name, rootFull, []string{app, label})
+	return err
+}
+
+func (f *FakeBackend) ContainerUnexportDesktopEntry(name, app string, rootFull bool) error {
+	_, err := f.run("unexportApp", name, rootFull, []string{app})
+	return err
+}
+
+func (f *FakeBackend) ContainerExportBin(name, binary, path string, rootFull bool) error {
+	_, err := f.run("exportBin", name, rootFull, []string{binary, path})
+	return err
+}
+
+func (f *FakeBackend) ContainerUnexportBin(name, binary string, rootFull bool) error {
+	_, err := f.run("unexportBin", name, rootFull, []string{binary})
+	return err
+}
+
+// formatContainerList renders containers as podman ps --format json does.
+func formatContainerList(containers []DBoxContainer) ([]byte, error) {
+	listed := make([]podmanContainer, 0, len(containers))
+	for _, c := range containers {
+		listed = append(listed, podmanContainer{
+			ID:        c.ID,
+			Names:     []string{c.Name},
+			Labels:    c.Labels,
+			Status:    c.Status,
+			CreatedAt: c.CreatedAt,
+		})
+	}
+
+	return json.Marshal(listed)
+}
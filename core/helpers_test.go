@@ -0,0 +1,117 @@
+package core
+
+import (
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/AuruOS/abg/settings"
+)
+
+// setupTestAbg points abg at a temporary tree and replaces the container
+// backend with a fake one, restoring both when the test ends.
+func setupTestAbg(t *testing.T) *FakeBackend {
+	t.Helper()
+
+	dir := t.TempDir()
+	t.Setenv("HOME", dir)
+
+	cnf := settings.NewAbgConfig(filepath.Join(dir, "usr"), "distrobox", "overlay")
+	for _, path := range []string{cnf.StacksPath, cnf.UserStacksPath, cnf.PkgManagersPath, cnf.UserPkgManagersPath, cnf.AbgStoragePath} {
+		err := os.MkdirAll(path, 0755)
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	previous := abg
+	abg = &Abg{Cnf: cnf}
+
+	fake := NewFakeBackend()
+	backendOverride = fake
+
+	t.Cleanup(func() {
+		abg = previous
+		backendOverride = nil
+	})
+
+	return fake
+}
+
+// writeTestFile writes a file, creating its directory.
+func writeTestFile(t *testing.T, path, content string) {
+	t.Helper()
+
+	err := os.MkdirAll(filepath.Dir(path), 0755)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = os.WriteFile(path, []byte(content), 0644)
+	if err != nil {
+		t.Fatal(err)
+	}
+}
+
+// testPkgManager is an apt-like package manager definition.
+const testPkgManager = `
+name: apt
+model: 2
+needsudo: true
+cmdinstall: apt install -y
+cmdlist: apt list --installed
+cmdremove: apt remove -y
+cmdsearch: apt search
+cmdupdate: apt update
+`
+
+// writeTestPkgManager writes the apt-like package manager to the user
+// package managers.
+func writeTestPkgManager(t *testing.T) {
+	t.Helper()
+
+	writeTestFile(t, filepath.Join(abg.Cnf.UserPkgManagersPath, "apt.yaml"), testPkgManager)
+}
+
+// concurrencyProbe records how many matching fake backend calls ran at the
+// same time.
+type concurrencyProbe struct {
+	mu         sync.Mutex
+	running    int
+	maxRunning int
+}
+
+// probeConcurrency slows the fake backend calls accepted by match down and
+// returns a probe counting how many of them overlap.
+func probeConcurrency(fake *FakeBackend, match func(call FakeCall) bool) *concurrencyProbe {
+	probe := &concurrencyProbe{}
+	fake.OnRun = func(call FakeCall) {
+		if !match(call) {
+			return
+		}
+
+		probe.mu.Lock()
+		probe.running++
+		probe.maxRunning = max(probe.maxRunning, probe.running)
+		probe.mu.Unlock()
+
+		time.Sleep(10 * time.Millisecond)
+
+		probe.mu.Lock()
+		probe.running--
+		probe.mu.Unlock()
+	}
+
+	return probe
+}
+
+// max returns the highest number of matching calls that ran at the same
+// time.
+func (p *concurrencyProbe) max() int {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	return p.maxRunning
+}
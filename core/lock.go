@@ -0,0 +1,210 @@
+package core
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+)
+
+// LockedPackage is a package pinned to an exact version in a lock file.
+type LockedPackage struct {
+	Name    string
+	Version string
+	Arch    string
+}
+
+// LockFile records the exact package set installed in a subsystem.
+type LockFile struct {
+	SubSystem  string
+	Stack      string
+	Base       string
+	PkgManager string
+	Packages   []LockedPackage
+	CreatedAt  time.Time
+}
+
+// LockFilePath returns the default lock file path of a subsystem.
+func LockFilePath(subSystem *SubSystem) string {
+	return filepath.Join(abg.Cnf.AbgStoragePath, "locks", storageName(subSystem.InternalName, subSystem.IsRootfull)+".json")
+}
+
+// InstalledPackages returns the packages installed in the subsystem, parsed
+// from the output of the package manager list command.
+func (s *SubSystem) InstalledPackages() ([]LockedPackage, error) {
+	pkgManager, err := s.Stack.GetPkgManager()
+	if err != nil {
+		return nil, err
+	}
+
+	if pkgManager.CmdList == "" {
+		return nil, errors.New("package manager has no list command")
+	}
+
+	out, err := s.Query(pkgManager.GenCmd(pkgManager.CmdList)...)
+	if err != nil {
+		return nil, err
+	}
+
+	parsed, err := pkgManager.ParseOutput("list", out)
+	if err != nil {
+		return nil, err
+	}
+
+	packages := make([]LockedPackage, 0, len(parsed))
+	for _, pkg := range parsed {
+		packages = append(packages, LockedPackage{Name: pkg.Name, Version: pkg.Version, Arch: pkg.Arch})
+	}
+
+	return packages, nil
+}
+
+// Lock generates the lock file of the subsystem from its installed packages.
+func (s *SubSystem) Lock() (*LockFile, error) {
+	packages, err := s.InstalledPackages()
+	if err != nil {
+		return nil, err
+	}
+
+	return &LockFile{
+		SubSystem:  s.Name,
+		Stack:      s.Stack.Name,
+		Base:       s.Stack.Base,
+		PkgManager: s.Stack.PkgManager,
+		Packages:   packages,
+		CreatedAt:  time.Now(),
+	}, nil
+}
+
+// UpdateLock regenerates the lock file of the subsystem in its default
+// location.
+func (s *SubSystem) UpdateLock() error {
+	lock, err := s.Lock()
+	if err != nil {
+		return err
+	}
+
+	return lock.Save(LockFilePath(s))
+}
+
+// Sync installs the packages of the lock file missing from the subsystem.
+// When locked is set, packages are installed at the exact locked version.
+// It returns the number of packages installed.
+func (s *SubSystem) Sync(lock *LockFile, locked bool) (int, error) {
+	if lock.PkgManager != s.Stack.PkgManager {
+		return 0, errors.New("lock file was generated for a different package manager")
+	}
+
+	pkgManager, err := s.Stack.GetPkgManager()
+	if err != nil {
+		return 0, err
+	}
+
+	installed, err := s.InstalledPackages()
+	if err != nil {
+		return 0, err
+	}
+
+	installedVersions := map[string]string{}
+	for _, pkg := range installed {
+		installedVersions[pkg.Name] = pkg.Version
+	}
+
+	names := make([]string, 0)
+	specs := make([]string, 0)
+	for _, pkg := range lock.Packages {
+		version, ok := installedVersions[pkg.Name]
+		if ok && (!locked || version == pkg.Version) {
+			continue
+		}
+
+		if locked {
+			specs = append(specs, lockedPackageSpec(pkgManager.Name, pkg))
+		} else {
+			specs = append(specs, pkg.Name)
+		}
+		names = append(names, pkg.Name)
+	}
+
+	if len(specs) == 0 {
+		return 0, nil
+	}
+
+	_, err = s.Exec(false, false, pkgManager.GenCmd(pkgManager.CmdInstall, specs...)...)
+	if err != nil {
+		return 0, fmt.Errorf("failed to install %s: %w", strings.Join(specs, " "), err)
+	}
+
+	// Recorded as installed by the user, so a reset restores them
+	err = s.TrackInstalled(names...)
+	if err != nil {
+		return len(specs), err
+	}
+
+	return len(specs), s.UpdateLock()
+}
+
+// LoadLockFile loads a lock file from the specified path.
+func LoadLockFile(path string) (*LockFile, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, errors.New("lock file not found")
+		}
+		return nil, err
+	}
+
+	lock := &LockFile{}
+	err = json.Unmarshal(data, lock)
+	if err != nil {
+		return nil, err
+	}
+
+	return lock, nil
+}
+
+// Save writes the lock file to the specified path.
+func (l *LockFile) Save(path string) error {
+	if IsDryRun() {
+		printDryRun("write %s", path)
+		return nil
+	}
+
+	sort.Slice(l.Packages, func(i, j int) bool {
+		return l.Packages[i].Name < l.Packages[j].Name
+	})
+
+	data, err := json.MarshalIndent(l, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	err = os.MkdirAll(filepath.Dir(path), 0755)
+	if err != nil {
+		return err
+	}
+
+	return os.WriteFile(path, data, 0644)
+}
+
+// lockedPackageSpec returns the install argument pinning a package to its
+// locked version, in the syntax of the given package manager.
+func lockedPackageSpec(pkgManager string, pkg LockedPackage) string {
+	if pkg.Version == "" {
+		return pkg.Name
+	}
+
+	switch pkgManager {
+	case "apt", "apt-get", "nala", "apk", "zypper":
+		return pkg.Name + "=" + pkg.Version
+	case "dnf", "yum", "microdnf":
+		return pkg.Name + "-" + pkg.Version
+	default:
+		// pacman and derivatives can't install a given version from the repos
+		return pkg.Name
+	}
+}
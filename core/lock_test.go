@@ -0,0 +1,50 @@
+package core
+
+import (
+	"errors"
+	"os"
+	"slices"
+	"testing"
+)
+
+func TestSyncFailingInstall(t *testing.T) {
+	fake := setupTestAbg(t)
+	writeTestPkgManager(t)
+
+	subSystem := &SubSystem{InternalName: genInternalName("dev"), Name: "dev", Stack: &Stack{Name: "ubuntu", PkgManager: "apt"}}
+	fake.Outputs["sudo apt list --installed"] = "htop/jammy,now 3.0.5-7build2 amd64 [installed]\n"
+
+	lock := &LockFile{
+		PkgManager: "apt",
+		Packages:   []LockedPackage{{Name: "htop", Version: "3.0.5-7build2"}, {Name: "git", Version: "1:2.34.1-1ubuntu1"}},
+	}
+
+	fake.Errors["sudo apt install -y git=1:2.34.1-1ubuntu1"] = errors.New("exit status 100")
+	installedN, err := subSystem.Sync(lock, true)
+	if err == nil {
+		t.Fatal("a failing install should fail the sync")
+	}
+	if installedN != 0 {
+		t.Errorf("installed %d packages, want 0", installedN)
+	}
+	if _, err := os.Stat(LockFilePath(subSystem)); !os.IsNotExist(err) {
+		t.Error("the lock file should not be updated after a failed sync")
+	}
+
+	delete(fake.Errors, "sudo apt install -y git=1:2.34.1-1ubuntu1")
+	installedN, err = subSystem.Sync(lock, true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if installedN != 1 {
+		t.Errorf("installed %d packages, want 1", installedN)
+	}
+
+	tracking, err := subSystem.LoadTracking()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !slices.Equal(tracking.Installed, []string{"git"}) {
+		t.Errorf("tracked %v as installed, want [git]", tracking.Installed)
+	}
+}
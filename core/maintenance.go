@@ -0,0 +1,188 @@
+package core
+
+import (
+	"fmt"
+	"strings"
+	"sync"
+)
+
+// DefaultMaintenanceJobs is the number of subsystems updated or upgraded at
+// the same time by RunMaintenance.
+const DefaultMaintenanceJobs = 4
+
+// MaintenanceResult is the outcome of updating or upgrading a subsystem.
+type MaintenanceResult struct {
+	SubSystem string
+	Stack     string
+	Changed   int // Number of packages added, removed or changed, -1 if unknown
+	Output    string
+	Err       error
+	StopErr   error // Error stopping again a subsystem that was stopped
+}
+
+// IsRunning informs whether the subsystem container is running.
+func (s *SubSystem) IsRunning() bool {
+	return strings.HasPrefix(s.Status, "Up") || strings.HasPrefix(strings.ToLower(s.Status), "running")
+}
+
+// RunMaintenance runs the update or upgrade command of every subsystem.
+// Subsystems whose package manager declares an AssumeYes flag run
+// unattended, at most jobs at a time, with their output captured. The
+// others may ask for a confirmation, so they run one at a time attached to
+// the terminal once the unattended ones are done. Stopped subsystems are
+// started for the duration of the command and stopped again afterwards.
+// The done callback, if set, is called as each subsystem completes.
+// Results are returned in the order of ListSubSystems.
+func RunMaintenance(command string, jobs int, done func(*MaintenanceResult)) ([]*MaintenanceResult, error) {
+	if command != "update" && command != "upgrade" {
+		return nil, fmt.Errorf("unknown maintenance command %s", command)
+	}
+
+	if jobs < 1 {
+		jobs = DefaultMaintenanceJobs
+	}
+
+	subSystems, err := ListSubSystems(false, false)
+	if err != nil {
+		return nil, err
+	}
+
+	results := make([]*MaintenanceResult, len(subSystems))
+	slots := make(chan struct{}, jobs)
+	attached := make([]int, 0)
+
+	var wg sync.WaitGroup
+	var doneMu sync.Mutex
+	for i, subSystem := range subSystems {
+		if !subSystem.runsUnattended() {
+			attached = append(attached, i)
+			continue
+		}
+
+		wg.Add(1)
+		go func(i int, subSystem *SubSystem) {
+			defer wg.Done()
+
+			slots <- struct{}{}
+			results[i] = subSystem.runMaintenance(command, false)
+			<-slots
+
+			if done != nil {
+				doneMu.Lock()
+				done(results[i])
+				doneMu.Unlock()
+			}
+		}(i, subSystem)
+	}
+	wg.Wait()
+
+	for _, i := range attached {
+		results[i] = subSystems[i].runMaintenance(command, true)
+		if done != nil {
+			done(results[i])
+		}
+	}
+
+	return results, nil
+}
+
+// runsUnattended informs whether the maintenance commands of the subsystem
+// can run without a terminal. A subsystem whose package manager can't be
+// loaded fails right away, so it doesn't need one either.
+func (s *SubSystem) runsUnattended() bool {
+	pkgManager, err := s.Stack.GetPkgManager()
+	return err != nil || pkgManager.AssumeYes != ""
+}
+
+// runMaintenance runs the update or upgrade command of the subsystem and
+// counts the packages it changed. Attached commands get the terminal,
+// the others get the AssumeYes flag and their output is captured.
+func (s *SubSystem) runMaintenance(command string, attached bool) *MaintenanceResult {
+	result := &MaintenanceResult{
+		SubSystem: s.Name,
+		Stack:     s.Stack.Name,
+		Changed:   -1,
+	}
+
+	pkgManager, err := s.Stack.GetPkgManager()
+	if err != nil {
+		result.Err = err
+		return result
+	}
+
+	realCommand := pkgManager.CmdUpdate
+	if command == "upgrade" {
+		realCommand = pkgManager.CmdUpgrade
+	}
+	if realCommand == "" {
+		result.Err = fmt.Errorf("package manager %s has no %s command", pkgManager.Name, command)
+		return result
+	}
+
+	backend, err := NewBackend()
+	if err != nil {
+		result.Err = err
+		return result
+	}
+
+	if !s.IsRunning() {
+		err := backend.ContainerStart(s.InternalName, s.IsRootfull)
+		if err != nil {
+			result.Err = fmt.Errorf("starting container: %w", err)
+			return result
+		}
+
+		defer func() {
+			result.StopErr = backend.ContainerStop(s.InternalName, s.IsRootfull)
+		}()
+	}
+
+	before, beforeErr := s.InstalledPackages()
+
+	if attached {
+		_, result.Err = backend.ContainerExec(s.InternalName, false, false, s.IsRootfull, false, pkgManager.GenCmd(realCommand)...)
+	} else {
+		// The output is captured and stderr kept out of the terminal so
+		// concurrent subsystems don't interleave, a failure carries what
+		// was written to stderr
+		result.Output, result.Err = backend.ContainerExec(s.InternalName, true, true, s.IsRootfull, false, pkgManager.GenCmd(realCommand, pkgManager.AssumeYes)...)
+	}
+	if result.Err != nil {
+		return result
+	}
+
+	after, afterErr := s.InstalledPackages()
+	if beforeErr == nil && afterErr == nil {
+		result.Changed = countChangedPackages(before, after)
+	}
+
+	if command == "upgrade" && afterErr == nil {
+		err := s.UpdateLock()
+		if err != nil {
+			result.Err = fmt.Errorf("updating lock file: %w", err)
+		}
+	}
+
+	return result
+}
+
+// countChangedPackages returns the number of packages added, removed or
+// whose version changed between two package lists.
+func countChangedPackages(before, after []LockedPackage) int {
+	versions := map[string]string{}
+	for _, pkg := range before {
+		versions[pkg.Name+"."+pkg.Arch] = pkg.Version
+	}
+
+	changed := 0
+	for _, pkg := range after {
+		key := pkg.Name + "." + pkg.Arch
+		version, ok := versions[key]
+		if !ok || version != pkg.Version {
+			changed++
+		}
+		delete(versions, key)
+	}
+
+	return changed + len(versions)
+}
@@ -0,0 +1,105 @@
+package core
+
+import (
+	"errors"
+	"path/filepath"
+	"slices"
+	"testing"
+)
+
+// addTestContainers adds running rootless subsystem containers of the
+// ubuntu stack to the fake backend.
+func addTestContainers(fake *FakeBackend, names ...string) {
+	for _, name := range names {
+		fake.Containers[false] = append(fake.Containers[false], DBoxContainer{
+			ID:     name,
+			Name:   genInternalName(name),
+			Status: "Up 2 hours",
+			Labels: map[string]string{"manager": "abg", "name": name, "stack": "ubuntu"},
+		})
+	}
+}
+
+func writeMaintenanceStack(t *testing.T, assumeYes string) {
+	t.Helper()
+
+	pkgManager := testPkgManager + "cmdupgrade: apt upgrade\n"
+	if assumeYes != "" {
+		pkgManager += "assumeyes: " + assumeYes + "\n"
+	}
+	writeTestFile(t, filepath.Join(abg.Cnf.UserPkgManagersPath, "apt.yaml"), pkgManager)
+	writeTestFile(t, filepath.Join(abg.Cnf.UserStacksPath, "ubuntu.yaml"), `
+name: ubuntu
+base: docker.io/library/ubuntu:22.04
+pkgmanager: apt
+`)
+}
+
+func TestRunMaintenanceUnattended(t *testing.T) {
+	fake := setupTestAbg(t)
+	writeMaintenanceStack(t, "-y")
+	addTestContainers(fake, "one", "two", "three", "four", "five", "six")
+
+	probe := probeConcurrency(fake, func(call FakeCall) bool { return call.Method == "exec" })
+
+	results, err := RunMaintenance("upgrade", 2, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(results) != 6 {
+		t.Fatalf("expected 6 results, got %d", len(results))
+	}
+	if probe.max() != 2 {
+		t.Errorf("%d subsystems upgraded at the same time, want 2", probe.max())
+	}
+
+	for _, call := range fake.CallsTo("exec") {
+		if !call.Captured || !slices.Equal(call.Args, []string{"sudo", "apt", "upgrade", "-y"}) {
+			t.Errorf("unexpected upgrade call: %+v", call)
+		}
+	}
+}
+
+func TestRunMaintenanceAttachedWithoutAssumeYes(t *testing.T) {
+	fake := setupTestAbg(t)
+	writeMaintenanceStack(t, "")
+	addTestContainers(fake, "one", "two")
+
+	results, err := RunMaintenance("upgrade", 4, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, result := range results {
+		if result.Err != nil {
+			t.Errorf("%s failed: %v", result.SubSystem, result.Err)
+		}
+	}
+
+	execs := fake.CallsTo("exec")
+	if len(execs) != 2 {
+		t.Fatalf("expected 2 upgrades, got %+v", execs)
+	}
+	for _, call := range execs {
+		if call.Captured || !slices.Equal(call.Args, []string{"sudo", "apt", "upgrade"}) {
+			t.Errorf("upgrades asking for a confirmation should get the terminal: %+v", call)
+		}
+	}
+}
+
+func TestRunMaintenanceReportsFailure(t *testing.T) {
+	fake := setupTestAbg(t)
+	writeMaintenanceStack(t, "-y")
+	addTestContainers(fake, "one")
+	fake.Errors["sudo apt upgrade -y"] = errors.New("E: Unable to locate package")
+
+	results, err := RunMaintenance("upgrade", 1, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(results) != 1 || results[0].Err == nil || results[0].Err.Error() != "E: Unable to locate package" {
+		t.Errorf("the failure should be reported: %+v", results)
+	}
+}
@@ -0,0 +1,137 @@
+package core
+
+import (
+	"fmt"
+	"path/filepath"
+	"strings"
+)
+
+// Mount types, a mount without type is a bind mount.
+const (
+	MountTypeBind   = "bind"
+	MountTypeTmpfs  = "tmpfs"
+	MountTypeDevice = "device"
+)
+
+// Mount is a host directory, a tmpfs or a device node made available in a
+// subsystem. Stacks and subsystems declare them, the stack ones first.
+type Mount struct {
+	Type     string // bind (default), tmpfs or device
+	Source   string // Host path of bind mounts and devices
+	Target   string // Path in the container, defaults to the source
+	ReadOnly bool   // Only for bind mounts
+}
+
+// ParseMount parses a mount given on the command line: src[:target][:ro|rw]
+// for bind mounts, target for tmpfs and src[:target] for devices.
+func ParseMount(mountType, value string) (Mount, error) {
+	mount := Mount{Type: mountType}
+
+	parts := strings.Split(value, ":")
+	switch mountType {
+	case MountTypeTmpfs:
+		mount.Target = value
+	case MountTypeBind:
+		last := parts[len(parts)-1]
+		if len(parts) > 1 && (last == "ro" || last == "rw") {
+			mount.ReadOnly = last == "ro"
+			parts = parts[:len(parts)-1]
+		}
+		fallthrough
+	case MountTypeDevice:
+		if len(parts) > 2 {
+			return mount, fmt.Errorf("invalid %s mount %q", mountType, value)
+		}
+		mount.Source = parts[0]
+		if len(parts) == 2 {
+			mount.Target = parts[1]
+		}
+	default:
+		return mount, fmt.Errorf("unknown mount type %q", mountType)
+	}
+
+	return mount, mount.Validate()
+}
+
+// kind returns the type of the mount, bind when it is not set.
+func (m Mount) kind() string {
+	if m.Type == "" {
+		return MountTypeBind
+	}
+	return m.Type
+}
+
+// target returns the path of the mount in the container.
+func (m Mount) target() string {
+	if m.Target == "" {
+		return m.Source
+	}
+	return m.Target
+}
+
+// Validate checks the mount paths are absolute and set for its type.
+func (m Mount) Validate() error {
+	switch m.kind() {
+	case MountTypeBind, MountTypeDevice:
+		if !filepath.IsAbs(m.Source) {
+			return fmt.Errorf("%s mount source %q must be an absolute path", m.kind(), m.Source)
+		}
+	case MountTypeTmpfs:
+		if m.Source != "" {
+			return fmt.Errorf("tmpfs mount %q can't have a source", m.Target)
+		}
+	default:
+		return fmt.Errorf("unknown mount type %q", m.Type)
+	}
+
+	if !filepath.IsAbs(m.target()) {
+		return fmt.Errorf("%s mount target %q must be an absolute path", m.kind(), m.target())
+	}
+	if m.ReadOnly && m.kind() != MountTypeBind {
+		return fmt.Errorf("only bind mounts can be read-only")
+	}
+
+	return nil
+}
+
+// String returns the mount in the form ParseMount reads, prefixed by its
+// type.
+func (m Mount) String() string {
+	switch m.kind() {
+	case MountTypeTmpfs:
+		return "tmpfs " + m.target()
+	case MountTypeDevice:
+		return "device " + m.Source + ":" + m.target()
+	}
+
+	mode := "rw"
+	if m.ReadOnly {
+		mode = "ro"
+	}
+	return fmt.Sprintf("bind %s:%s:%s", m.Source, m.target(), mode)
+}
+
+// addMounts adds the mounts to the extras, in the engine formats.
+func (e *ContainerExtras) addMounts(mounts []Mount) error {
+	for _, mount := range mounts {
+		err := mount.Validate()
+		if err != nil {
+			return err
+		}
+
+		switch mount.kind() {
+		case MountTypeBind:
+			volume := mount.Source + ":" + mount.target()
+			if mount.ReadOnly {
+				volume += ":ro"
+			}
+			e.Volumes = append(e.Volumes, volume)
+		case MountTypeTmpfs:
+			e.Tmpfs = append(e.Tmpfs, mount.target())
+		case MountTypeDevice:
+			e.Devices = append(e.Devices, mount.Source+":"+mount.target())
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,99 @@
+package core
+
+import (
+	"path/filepath"
+	"slices"
+	"testing"
+)
+
+func TestParseMount(t *testing.T) {
+	tests := []struct {
+		mountType string
+		value     string
+		want      Mount
+	}{
+		{MountTypeBind, "/srv/data", Mount{Type: MountTypeBind, Source: "/srv/data"}},
+		{MountTypeBind, "/srv/data:/data:ro", Mount{Type: MountTypeBind, Source: "/srv/data", Target: "/data", ReadOnly: true}},
+		{MountTypeBind, "/srv/data:rw", Mount{Type: MountTypeBind, Source: "/srv/data"}},
+		{MountTypeTmpfs, "/scratch", Mount{Type: MountTypeTmpfs, Target: "/scratch"}},
+		{MountTypeDevice, "/dev/kvm", Mount{Type: MountTypeDevice, Source: "/dev/kvm"}},
+	}
+
+	for _, test := range tests {
+		got, err := ParseMount(test.mountType, test.value)
+		if err != nil {
+			t.Errorf("ParseMount(%s, %q): %s", test.mountType, test.value, err)
+			continue
+		}
+		if got != test.want {
+			t.Errorf("ParseMount(%s, %q) = %+v, want %+v", test.mountType, test.value, got, test.want)
+		}
+	}
+
+	for _, value := range []string{"data", "/a:/b:/c", "/a:relative"} {
+		if _, err := ParseMount(MountTypeBind, value); err == nil {
+			t.Errorf("ParseMount(bind, %q) should fail", value)
+		}
+	}
+	if _, err := ParseMount("nfs", "/a"); err == nil {
+		t.Error("unknown mount types should be rejected")
+	}
+}
+
+func TestCreateWithMounts(t *testing.T) {
+	fake := setupTestAbg(t)
+
+	writeTestFile(t, filepath.Join(abg.Cnf.UserStacksPath, "base.yaml"), `
+name: base
+base: docker.io/library/ubuntu:22.04
+pkgmanager: apt
+mounts:
+  - source: /srv/data
+    target: /data
+    readonly: true
+  - type: tmpfs
+    target: /scratch
+`)
+	writeTestFile(t, filepath.Join(abg.Cnf.UserStacksPath, "dev.yaml"), `
+name: dev
+extends: base
+mounts:
+  - type: device
+    source: /dev/kvm
+`)
+
+	stack, err := LoadStack("dev")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(stack.Mounts) != 3 {
+		t.Fatalf("mounts should be inherited, got %+v", stack.Mounts)
+	}
+
+	subSystem := &SubSystem{
+		InternalName: genInternalName("dev"),
+		Name:         "dev",
+		Stack:        stack,
+		Mounts:       []Mount{{Source: "/home/me/src", Target: "/src"}},
+	}
+	err = subSystem.Create()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	extras := fake.CallsTo("create")[0].Extras
+	if !slices.Equal(extras.Volumes, []string{"/srv/data:/data:ro", "/home/me/src:/src"}) {
+		t.Errorf("volumes = %v", extras.Volumes)
+	}
+	if !slices.Equal(extras.Tmpfs, []string{"/scratch"}) || !slices.Equal(extras.Devices, []string{"/dev/kvm:/dev/kvm"}) {
+		t.Errorf("tmpfs = %v, devices = %v", extras.Tmpfs, extras.Devices)
+	}
+
+	loaded, err := LoadSubSystem("dev", false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(loaded.Mounts) != 1 || loaded.Mounts[0].Target != "/src" {
+		t.Errorf("subsystem mounts were not persisted: %+v", loaded.Mounts)
+	}
+}
@@ -29,7 +29,13 @@ type PkgManager struct {
 	CmdShow       string
 	CmdUpdate     string
 	CmdUpgrade    string
-	BuiltIn       bool // Built-in managers can't be removed
+	CmdAddRepo    string     // Optional, adds a repository declared by a stack
+	CmdImportKey  string     // Optional, imports the signing key of a repository
+	AssumeYes     string     // Optional, flag answering yes to the update and upgrade prompts
+	ParseList     *PkgParser // Optional, parses the CmdList output
+	ParseSearch   *PkgParser // Optional, parses the CmdSearch output
+	ParseShow     *PkgParser // Optional, parses the CmdShow output
+	BuiltIn       bool       // Built-in managers can't be removed
 }
 
 // NewPkgManager creates a new instance of PkgManager.
@@ -98,6 +104,34 @@ func (pm *PkgManager) Export(path string) error {
 	return os.WriteFile(filePath, data, 0644)
 }
 
+// GetCommand returns the command of the package manager for the given
+// operation name, e.g. "install", or an empty string if unknown.
+func (pm *PkgManager) GetCommand(command string) string {
+	switch command {
+	case "autoRemove":
+		return pm.CmdAutoRemove
+	case "clean":
+		return pm.CmdClean
+	case "install":
+		return pm.CmdInstall
+	case "list":
+		return pm.CmdList
+	case "purge":
+		return pm.CmdPurge
+	case "remove":
+		return pm.CmdRemove
+	case "search":
+		return pm.CmdSearch
+	case "show":
+		return pm.CmdShow
+	case "update":
+		return pm.CmdUpdate
+	case "upgrade":
+		return pm.CmdUpgrade
+	}
+	return ""
+}
+
 // GenCmd builds the full command for the container environment.
 func (pm *PkgManager) GenCmd(cmd string, args ...string) []string {
 	var finalArgs []string
@@ -107,7 +141,7 @@ func (pm *PkgManager) GenCmd(cmd string, args ...string) []string {
 	}
 
 	if pm.Model == 0 || pm.Model == 1 {
-		fmt.Println("!!! DEPRECATION WARNING: Model 1 is deprecated. Please update your ABG package manager.")
+		fmt.Fprintln(os.Stderr, "!!! DEPRECATION WARNING: Model 1 is deprecated. Please update your ABG package manager.")
 		finalArgs = append(finalArgs, pm.Name, cmd)
 	} else {
 		finalArgs = append(finalArgs, strings.Fields(cmd)...)
@@ -0,0 +1,133 @@
+package core
+
+import (
+	"path/filepath"
+	"slices"
+	"testing"
+)
+
+func TestLoadPkgManager(t *testing.T) {
+	setupTestAbg(t)
+
+	writeTestFile(t, filepath.Join(abg.Cnf.PkgManagersPath, "apt.yaml"), testPkgManager+"builtin: true\n")
+
+	pm, err := LoadPkgManager("apt")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if pm.Model != 2 || !pm.NeedSudo || pm.CmdInstall != "apt install -y" || !pm.BuiltIn {
+		t.Errorf("unexpected package manager: %+v", pm)
+	}
+
+	// User package managers take precedence over the built-in ones
+	writeTestFile(t, filepath.Join(abg.Cnf.UserPkgManagersPath, "apt.yml"), `
+name: apt
+model: 2
+cmdinstall: apt-get install -y
+`)
+
+	pm, err = LoadPkgManager("apt")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if pm.CmdInstall != "apt-get install -y" {
+		t.Errorf("user package manager not preferred, install is %q", pm.CmdInstall)
+	}
+
+	_, err = LoadPkgManager("missing")
+	if err == nil {
+		t.Error("loading a missing package manager should fail")
+	}
+}
+
+func TestLoadPkgManagerDefaultsToModel1(t *testing.T) {
+	setupTestAbg(t)
+
+	writeTestFile(t, filepath.Join(abg.Cnf.UserPkgManagersPath, "old.yaml"), `
+name: old
+cmdinstall: install
+`)
+
+	pm, err := LoadPkgManager("old")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if pm.Model != 1 {
+		t.Errorf("expected model 1 for a definition without model, got %d", pm.Model)
+	}
+}
+
+func TestGenCmd(t *testing.T) {
+	tests := []struct {
+		name string
+		pm   *PkgManager
+		cmd  string
+		args []string
+		want []string
+	}{
+		{
+			name: "model 2",
+			pm:   &PkgManager{Model: 2, Name: "apt"},
+			cmd:  "apt install -y",
+			args: []string{"htop", "git"},
+			want: []string{"apt", "install", "-y", "htop", "git"},
+		},
+		{
+			name: "model 2 with sudo",
+			pm:   &PkgManager{Model: 2, Name: "apt", NeedSudo: true},
+			cmd:  "apt install -y",
+			args: []string{"htop"},
+			want: []string{"sudo", "apt", "install", "-y", "htop"},
+		},
+		{
+			name: "model 1",
+			pm:   &PkgManager{Model: 1, Name: "pacman"},
+			cmd:  "-S",
+			args: []string{"htop"},
+			want: []string{"pacman", "-S", "htop"},
+		},
+		{
+			name: "model 1 with sudo",
+			pm:   &PkgManager{Model: 1, Name: "pacman", NeedSudo: true},
+			cmd:  "-Syu",
+			want: []string{"sudo", "pacman", "-Syu"},
+		},
+		{
+			name: "unset model behaves as model 1",
+			pm:   &PkgManager{Name: "pacman"},
+			cmd:  "-S",
+			args: []string{"htop"},
+			want: []string{"pacman", "-S", "htop"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := tt.pm.GenCmd(tt.cmd, tt.args...)
+			if !slices.Equal(got, tt.want) {
+				t.Errorf("GenCmd() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestGenRepoCmdQuotesValues(t *testing.T) {
+	pm := &PkgManager{Model: 2, Name: "apt", NeedSudo: true}
+	repo := StackRepository{
+		Name:   "vendor",
+		Source: "deb [arch=amd64] https://example.org/apt stable main",
+		Key:    "https://example.org/it's.key",
+	}
+
+	got := pm.GenRepoCmd("curl -fsSL {key} | gpg --dearmor -o /etc/apt/keyrings/{name}.gpg", repo, repo.Key)
+	want := []string{"sudo", "sh", "-c", `curl -fsSL 'https://example.org/it'\''s.key' | gpg --dearmor -o /etc/apt/keyrings/'vendor'.gpg`}
+	if !slices.Equal(got, want) {
+		t.Errorf("GenRepoCmd() = %q, want %q", got, want)
+	}
+
+	got = pm.GenRepoCmd("add-apt-repository -y", repo, repo.Source)
+	want = []string{"sudo", "add-apt-repository", "-y", repo.Source}
+	if !slices.Equal(got, want) {
+		t.Errorf("GenRepoCmd() = %q, want %q", got, want)
+	}
+}
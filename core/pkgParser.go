@@ -0,0 +1,446 @@
+package core
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+// Package is a package as reported by a package manager.
+type Package struct {
+	Name        string `json:"name"`
+	Version     string `json:"version,omitempty"`
+	Arch        string `json:"arch,omitempty"`
+	Repo        string `json:"repo,omitempty"`
+	Description string `json:"description,omitempty"`
+}
+
+// PkgParser describes how to turn the output of a package manager command
+// into packages. Exactly one of Regex, Fields or KeyValue is expected.
+type PkgParser struct {
+	// Regex is matched against each line, its named groups (name, version,
+	// arch, repo, description) fill the package fields. Lines not matching
+	// are skipped.
+	Regex string
+	// Fields maps the columns of each line, split on Separator (whitespace
+	// when empty), to package fields. Empty names skip a column, the last
+	// column takes the rest of the line.
+	Fields    []string
+	Separator string
+	// KeyValue maps the keys of "Key: Value" lines to package fields, for
+	// commands printing one record per package separated by blank lines.
+	KeyValue map[string]string
+	// SkipLines is the number of header lines to ignore.
+	SkipLines int
+}
+
+// Parse parses the output of a package manager command.
+func (p *PkgParser) Parse(out string) ([]Package, error) {
+	lines := strings.Split(out, "\n")
+	if p.SkipLines > 0 {
+		if p.SkipLines >= len(lines) {
+			return []Package{}, nil
+		}
+		lines = lines[p.SkipLines:]
+	}
+
+	switch {
+	case p.Regex != "":
+		return p.parseRegex(lines)
+	case len(p.Fields) > 0:
+		return p.parseFields(lines), nil
+	case len(p.KeyValue) > 0:
+		return p.parseKeyValue(lines), nil
+	}
+
+	return nil, fmt.Errorf("empty parser")
+}
+
+func (p *PkgParser) parseRegex(lines []string) ([]Package, error) {
+	re, err := regexp.Compile(p.Regex)
+	if err != nil {
+		return nil, fmt.Errorf("invalid parser regex: %w", err)
+	}
+
+	packages := make([]Package, 0)
+	for _, line := range lines {
+		match := re.FindStringSubmatch(line)
+		if match == nil {
+			continue
+		}
+
+		pkg := Package{}
+		for i, group := range re.SubexpNames() {
+			setPackageField(&pkg, group, match[i])
+		}
+
+		if pkg.Name != "" {
+			packages = append(packages, pkg)
+		}
+	}
+
+	return packages, nil
+}
+
+func (p *PkgParser) parseFields(lines []string) []Package {
+	packages := make([]Package, 0)
+	for _, line := range lines {
+		if strings.TrimSpace(line) == "" {
+			continue
+		}
+
+		var columns []string
+		if p.Separator == "" {
+			columns = strings.Fields(line)
+			if len(columns) > len(p.Fields) {
+				// Let the last column take the rest of the line, descriptions
+				// usually contain spaces
+				rest := strings.Join(columns[len(p.Fields)-1:], " ")
+				columns = append(columns[:len(p.Fields)-1], rest)
+			}
+		} else {
+			columns = strings.SplitN(line, p.Separator, len(p.Fields))
+		}
+
+		pkg := Package{}
+		for i, field := range p.Fields {
+			if i < len(columns) {
+				setPackageField(&pkg, field, strings.TrimSpace(columns[i]))
+			}
+		}
+
+		if pkg.Name != "" {
+			packages = append(packages, pkg)
+		}
+	}
+
+	return packages
+}
+
+func (p *PkgParser) parseKeyValue(lines []string) []Package {
+	packages := make([]Package, 0)
+	current := Package{}
+
+	flush := func() {
+		if current.Name != "" {
+			packages = append(packages, current)
+		}
+		current = Package{}
+	}
+
+	for _, line := range lines {
+		if strings.TrimSpace(line) == "" {
+			flush()
+			continue
+		}
+
+		key, value, ok := strings.Cut(line, ":")
+		if !ok {
+			continue
+		}
+
+		if field, ok := p.KeyValue[strings.TrimSpace(key)]; ok {
+			setPackageField(&current, field, strings.TrimSpace(value))
+		}
+	}
+	flush()
+
+	return packages
+}
+
+// setPackageField sets a package field by its lowercase name, unknown
+// names are ignored.
+func setPackageField(pkg *Package, field, value string) {
+	switch field {
+	case "name":
+		pkg.Name = value
+	case "version":
+		pkg.Version = value
+	case "arch":
+		pkg.Arch = value
+	case "repo":
+		pkg.Repo = value
+	case "description":
+		pkg.Description = value
+	}
+}
+
+// ParseOutput parses the output of the list, search or show command using
+// the parser declared by the package manager. The list and search output of
+// well-known package managers is understood even without a parser.
+func (pm *PkgManager) ParseOutput(command, out string) ([]Package, error) {
+	var parser *PkgParser
+	switch command {
+	case "list":
+		parser = pm.ParseList
+	case "search":
+		parser = pm.ParseSearch
+	case "show":
+		parser = pm.ParseShow
+	}
+
+	if parser != nil {
+		return parser.Parse(out)
+	}
+
+	switch command {
+	case "list":
+		return parseListOutput(pm.Name, out), nil
+	case "search":
+		return parseSearchOutput(pm.Name, out), nil
+	}
+
+	return nil, fmt.Errorf("package manager %s does not declare a parser for %s", pm.Name, command)
+}
+
+// parseListOutput parses the list command output of well-known package
+// managers. Unknown package managers are expected to print the package name
+// and version as the first two fields of each line.
+func parseListOutput(pkgManager, out string) []Package {
+	if pkgManager == "zypper" {
+		return parseZypperTable(out)
+	}
+
+	packages := make([]Package, 0)
+
+	for _, line := range strings.Split(out, "\n") {
+		fields := strings.Fields(line)
+		if len(fields) == 0 {
+			continue
+		}
+
+		var pkg Package
+		switch pkgManager {
+		case "apt", "apt-get", "nala":
+			// bash/jammy,now 5.1-6ubuntu1 amd64 [installed]
+			if len(fields) < 2 || !strings.Contains(fields[0], "/") {
+				continue
+			}
+			if strings.Contains(line, "[") && !strings.Contains(line, "[installed") {
+				continue
+			}
+			name, repo, _ := strings.Cut(fields[0], "/")
+			pkg.Name = name
+			pkg.Repo = repo
+			pkg.Version = fields[1]
+			if len(fields) > 2 {
+				pkg.Arch = fields[2]
+			}
+		case "dnf", "yum", "microdnf":
+			// bash.x86_64  5.2.15-3.fc38  @anaconda
+			if len(fields) < 3 || strings.HasSuffix(line, "Packages") {
+				continue
+			}
+			dot := strings.LastIndex(fields[0], ".")
+			if dot <= 0 {
+				continue
+			}
+			pkg.Name = fields[0][:dot]
+			pkg.Arch = fields[0][dot+1:]
+			pkg.Version = fields[1]
+			if colon := strings.Index(pkg.Version, ":"); colon >= 0 {
+				pkg.Version = pkg.Version[colon+1:]
+			}
+			pkg.Repo = strings.TrimPrefix(fields[2], "@")
+		case "apk":
+			// bash-5.2.21-r0 from apk info -v, followed by the arch and
+			// origin with apk list --installed
+			name, version, ok := splitApkPackage(fields[0])
+			if !ok {
+				continue
+			}
+			pkg.Name = name
+			pkg.Version = version
+			if len(fields) > 1 && !strings.HasPrefix(fields[1], "{") {
+				pkg.Arch = fields[1]
+			}
+		default:
+			// bash 5.2.015-1
+			if len(fields) < 2 {
+				continue
+			}
+			pkg.Name = fields[0]
+			pkg.Version = fields[1]
+		}
+
+		packages = append(packages, pkg)
+	}
+
+	return packages
+}
+
+// apkReleaseRe matches the release suffix ending apk package versions.
+var apkReleaseRe = regexp.MustCompile(`^r[0-9]+$`)
+
+// splitApkPackage splits an apk name-version-release string, names may
+// contain dashes so the version is the part before the release.
+func splitApkPackage(nameVersion string) (string, string, bool) {
+	release := strings.LastIndex(nameVersion, "-")
+	if release <= 0 || !apkReleaseRe.MatchString(nameVersion[release+1:]) {
+		return "", "", false
+	}
+
+	version := strings.LastIndex(nameVersion[:release], "-")
+	if version <= 0 {
+		return "", "", false
+	}
+
+	return nameVersion[:version], nameVersion[version+1:], true
+}
+
+// parseZypperTable parses the table zypper search prints, with the columns
+// named in its header, e.g. for zypper search --installed-only --details:
+//
+//	S  | Name | Type    | Version    | Arch   | Repository
+//	---+------+---------+------------+--------+-----------
+//	i+ | bash | package | 5.2.15-2.1 | x86_64 | repo-oss
+//
+// Only package rows are kept.
+func parseZypperTable(out string) []Package {
+	packages := make([]Package, 0)
+
+	var columns []string
+	for _, line := range strings.Split(out, "\n") {
+		if !strings.Contains(line, "|") {
+			continue
+		}
+
+		cells := strings.Split(line, "|")
+		for i := range cells {
+			cells[i] = strings.TrimSpace(cells[i])
+		}
+
+		if columns == nil {
+			columns = cells
+			continue
+		}
+		if strings.HasPrefix(cells[0], "--") {
+			continue
+		}
+
+		var pkg Package
+		isPackage := true
+		for i, column := range columns {
+			if i >= len(cells) {
+				break
+			}
+
+			switch column {
+			case "Name":
+				pkg.Name = cells[i]
+			case "Version":
+				pkg.Version = cells[i]
+			case "Arch":
+				pkg.Arch = cells[i]
+			case "Repository":
+				pkg.Repo = cells[i]
+			case "Summary":
+				pkg.Description = cells[i]
+			case "Type":
+				isPackage = cells[i] == "package"
+			}
+		}
+
+		if isPackage && pkg.Name != "" {
+			packages = append(packages, pkg)
+		}
+	}
+
+	return packages
+}
+
+// parseSearchOutput parses the search command output of well-known package
+// managers. Indented lines are descriptions of the previous match. Unknown
+// package managers are expected to print the package name, optionally
+// followed by its version, as the first fields of each line.
+func parseSearchOutput(pkgManager, out string) []Package {
+	packages := make([]Package, 0)
+
+	for _, line := range strings.Split(out, "\n") {
+		if strings.TrimSpace(line) == "" {
+			continue
+		}
+
+		if line[0] == ' ' || line[0] == '\t' {
+			if len(packages) > 0 && packages[len(packages)-1].Description == "" {
+				packages[len(packages)-1].Description = strings.TrimSpace(line)
+			}
+			continue
+		}
+
+		var pkg Package
+		switch pkgManager {
+		case "apt", "apt-get", "nala":
+			// bash/jammy 5.1-6ubuntu1 amd64
+			fields := strings.Fields(line)
+			if !strings.Contains(fields[0], "/") {
+				continue
+			}
+			name, repo, _ := strings.Cut(fields[0], "/")
+			pkg.Name = name
+			pkg.Repo = strings.Split(repo, ",")[0]
+			if len(fields) > 1 {
+				pkg.Version = fields[1]
+			}
+			if len(fields) > 2 {
+				pkg.Arch = fields[2]
+			}
+		case "dnf", "yum", "microdnf":
+			// bash.x86_64 : The GNU Bourne Again shell
+			nameArch, description, ok := strings.Cut(line, " : ")
+			if !ok {
+				continue
+			}
+			nameArch = strings.TrimSpace(nameArch)
+			if dot := strings.LastIndex(nameArch, "."); dot > 0 {
+				pkg.Name = nameArch[:dot]
+				pkg.Arch = nameArch[dot+1:]
+			} else {
+				pkg.Name = nameArch
+			}
+			pkg.Description = strings.TrimSpace(description)
+		case "pacman", "yay", "paru":
+			// core/bash 5.2.015-1 [installed]
+			fields := strings.Fields(line)
+			repo, name, ok := strings.Cut(fields[0], "/")
+			if !ok {
+				continue
+			}
+			pkg.Name = name
+			pkg.Repo = repo
+			if len(fields) > 1 {
+				pkg.Version = fields[1]
+			}
+		default:
+			// bash 5.2.015-1
+			fields := strings.Fields(line)
+			if strings.HasSuffix(fields[0], ":") {
+				continue
+			}
+			pkg.Name = fields[0]
+			if len(fields) > 1 {
+				pkg.Version = fields[1]
+			}
+		}
+
+		packages = append(packages, pkg)
+	}
+
+	return packages
+}
+
+// Parsers returns the commands the package manager declares a parser for.
+func (pm *PkgManager) Parsers() []string {
+	parsers := make([]string, 0)
+	if pm.ParseList != nil {
+		parsers = append(parsers, "list")
+	}
+	if pm.ParseSearch != nil {
+		parsers = append(parsers, "search")
+	}
+	if pm.ParseShow != nil {
+		parsers = append(parsers, "show")
+	}
+
+	return parsers
+}
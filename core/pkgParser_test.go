@@ -0,0 +1,63 @@
+package core
+
+import (
+	"slices"
+	"testing"
+)
+
+func TestParseListOutput(t *testing.T) {
+	tests := []struct {
+		name       string
+		pkgManager string
+		out        string
+		want       []Package
+	}{
+		{
+			name:       "apk info",
+			pkgManager: "apk",
+			out:        "WARNING: opening /var/cache/apk: No such file or directory\nbusybox-1.36.1-r15\nca-certificates-bundle-20240226-r0\nlibcrypto3-3.1.4-r5\n",
+			want: []Package{
+				{Name: "busybox", Version: "1.36.1-r15"},
+				{Name: "ca-certificates-bundle", Version: "20240226-r0"},
+				{Name: "libcrypto3", Version: "3.1.4-r5"},
+			},
+		},
+		{
+			name:       "apk list",
+			pkgManager: "apk",
+			out:        "musl-utils-1.2.4_git20230717-r4 x86_64 {musl} (MIT AND BSD-2-Clause AND GPL-2.0-or-later) [installed]\n",
+			want:       []Package{{Name: "musl-utils", Version: "1.2.4_git20230717-r4", Arch: "x86_64"}},
+		},
+		{
+			name:       "zypper details",
+			pkgManager: "zypper",
+			out: "Loading repository data...\nReading installed packages...\n\n" +
+				"S  | Name           | Type    | Version      | Arch   | Repository\n" +
+				"---+----------------+---------+--------------+--------+------------------\n" +
+				"i+ | bash           | package | 5.2.15-2.1   | x86_64 | Main Repository\n" +
+				"i  | libzypp        | package | 17.31.23-1.1 | x86_64 | (System Packages)\n" +
+				"i  | patterns-base  | pattern | 20200505-1.1 | x86_64 | Main Repository\n",
+			want: []Package{
+				{Name: "bash", Version: "5.2.15-2.1", Arch: "x86_64", Repo: "Main Repository"},
+				{Name: "libzypp", Version: "17.31.23-1.1", Arch: "x86_64", Repo: "(System Packages)"},
+			},
+		},
+		{
+			name:       "zypper summary",
+			pkgManager: "zypper",
+			out: "S  | Name | Summary                | Type\n" +
+				"---+------+------------------------+--------\n" +
+				"i+ | bash | The GNU Bourne-Again Shell | package\n",
+			want: []Package{{Name: "bash", Description: "The GNU Bourne-Again Shell"}},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			got := parseListOutput(test.pkgManager, test.out)
+			if !slices.Equal(got, test.want) {
+				t.Errorf("parsed %+v, want %+v", got, test.want)
+			}
+		})
+	}
+}
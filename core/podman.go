@@ -0,0 +1,467 @@
+package core
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"syscall"
+)
+
+// PodmanBackend runs subsystems as plain podman containers, without
+// distrobox. The container shares the user home, network and IPC with the
+// host unless unshared or restricted by the security profile, and maps the
+// user with keep-id, so commands run as the host user. Images don't get a
+// sudo setup: commands prefixed by sudo are run as root instead.
+type PodmanBackend struct {
+	EngineBinary string
+}
+
+// NewPodmanBackend returns the podman backend.
+func NewPodmanBackend() (*PodmanBackend, error) {
+	engineBinary, err := exec.LookPath("podman")
+	if err != nil {
+		return nil, errors.New("the podman backend requires podman")
+	}
+
+	return &PodmanBackend{EngineBinary: engineBinary}, nil
+}
+
+// InstallsPackages informs whether CreateContainer installs the packages it
+// is given, the podman backend leaves that to the package manager.
+func (p *PodmanBackend) InstallsPackages() bool {
+	return false
+}
+
+// run runs a podman command. Queries don't change anything, so they are run
+// even in dry-run mode.
+func (p *PodmanBackend) run(args []string, captureOutput, muteOutput, rootFull, detached, query bool) ([]byte, error) {
+	entrypoint := p.EngineBinary
+	if rootFull {
+		entrypoint = "pkexec"
+		args = append([]string{p.EngineBinary}, args...)
+	}
+
+	cmd := exec.Command(entrypoint, args...)
+
+	if detached {
+		cmd.SysProcAttr = &syscall.SysProcAttr{Setpgid: true}
+	}
+
+	if !captureOutput && !muteOutput {
+		cmd.Stdout = os.Stdout
+	}
+	if !muteOutput {
+		cmd.Stderr = os.Stderr
+	}
+	// The prompts of a command whose output is captured can't be seen, it
+	// gets no input so it fails instead of waiting for an answer
+	if !captureOutput {
+		cmd.Stdin = os.Stdin
+	}
+
+	cmd.Env = append(os.Environ(), "CONTAINER_STORAGE_DRIVER="+abg.Cnf.StorageDriver)
+
+	if os.Getenv("ABG_VERBOSE") == "1" {
+		fmt.Println("Running a command:")
+		fmt.Printf("\tCommand: %s\n", cmd.String())
+		fmt.Printf("\tcaptureOutput: %v\n\tmuteOutput: %v\n\trootFull: %v\n\tdetachedMode: %v\n",
+			captureOutput, muteOutput, rootFull, detached)
+	}
+
+	if IsDryRun() && !query {
+		printDryRun("CONTAINER_STORAGE_DRIVER=%s %s", abg.Cnf.StorageDriver, cmd.String())
+		return nil, nil
+	}
+
+	if detached {
+		return nil, cmd.Start()
+	}
+
+	if captureOutput {
+		output, err := cmd.Output()
+		if err != nil {
+			if exitErr, ok := err.(*exec.ExitError); ok && len(bytes.TrimSpace(exitErr.Stderr)) > 0 {
+				return output, errors.New(string(exitErr.Stderr))
+			}
+		}
+		return output, err
+	}
+
+	return nil, cmd.Run()
+}
+
+func (p *PodmanBackend) CreateContainer(name, image string, packages []string, home string, labels map[string]string, withInit, rootFull, unshared, withNvidia bool, hostname string, extras ContainerExtras) error {
+	if len(packages) > 0 {
+		return errors.New("the podman backend can't install packages at creation")
+	}
+
+	args, err := p.createArgs(name, image, home, labels, withInit, rootFull, unshared, withNvidia, hostname, extras)
+	if err != nil {
+		return err
+	}
+
+	_, err = p.run(args, false, false, rootFull, false, false)
+	return err
+}
+
+// createArgs builds the podman create arguments of a container.
+func (p *PodmanBackend) createArgs(name, image string, home string, labels map[string]string, withInit, rootFull, unshared, withNvidia bool, hostname string, extras ContainerExtras) ([]string, error) {
+	userHome, err := os.UserHomeDir()
+	if err != nil {
+		return nil, err
+	}
+
+	if hostname == "" {
+		hostname, err = os.Hostname()
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	args := []string{
+		"create",
+		"--name", name,
+		"--hostname", hostname,
+		"--security-opt", "label=disable",
+		"--label", "manager=abg",
+	}
+
+	if !extras.IsolateHome {
+		args = append(args, "--volume", userHome+":"+userHome+":rslave", "--env", "HOME="+userHome)
+	}
+
+	if !rootFull {
+		args = append(args, "--userns", "keep-id")
+	}
+
+	for k, v := range labels {
+		args = append(args, "--label", fmt.Sprintf("%s=%s", k, v))
+	}
+
+	if home != "" {
+		args = append(args, "--volume", home+":"+home, "--env", "HOME="+home)
+	}
+
+	switch {
+	case extras.Network == NetworkNone:
+		args = append(args, "--network", "none")
+	case !unshared && extras.Network != NetworkPrivate:
+		args = append(args, "--network", "host")
+	}
+
+	if !unshared {
+		args = append(args, "--ipc", "host")
+		if runtimeDir := os.Getenv("XDG_RUNTIME_DIR"); runtimeDir != "" {
+			args = append(args, "--volume", runtimeDir+":"+runtimeDir, "--env", "XDG_RUNTIME_DIR="+runtimeDir)
+		}
+		if _, err := os.Stat("/tmp/.X11-unix"); err == nil {
+			args = append(args, "--volume", "/tmp/.X11-unix:/tmp/.X11-unix")
+		}
+		for _, env := range []string{"DISPLAY", "WAYLAND_DISPLAY"} {
+			if value := os.Getenv(env); value != "" {
+				args = append(args, "--env", env+"="+value)
+			}
+		}
+	}
+
+	if hasNvidiaGPU() && withNvidia {
+		args = append(args, "--device", "nvidia.com/gpu=all")
+	}
+
+	for _, volume := range extras.Volumes {
+		args = append(args, "--volume", volume)
+	}
+	for _, tmpfs := range extras.Tmpfs {
+		args = append(args, "--tmpfs", tmpfs)
+	}
+	for _, device := range extras.Devices {
+		args = append(args, "--device", device)
+	}
+	for _, env := range extras.Env {
+		args = append(args, "--env", env)
+	}
+	for _, capability := range extras.CapDrop {
+		args = append(args, "--cap-drop", capability)
+	}
+	if extras.ReadOnlyRoot {
+		args = append(args, "--read-only")
+	}
+	args = append(args, extras.EngineFlags...)
+
+	// With keep-id the container process runs as the user, init has to
+	// run as root
+	if withInit {
+		args = append(args, "--user", "root", "--systemd", "always", image, "/sbin/init")
+	} else {
+		args = append(args, image, "sleep", "infinity")
+	}
+
+	return args, nil
+}
+
+func (p *PodmanBackend) ListContainers(rootFull bool) ([]DBoxContainer, error) {
+	return listEngineContainers("podman", func(args ...string) ([]byte, error) {
+		return p.run(args, true, true, rootFull, false, true)
+	})
+}
+
+func (p *PodmanBackend) GetContainer(name string, rootFull bool) (*DBoxContainer, error) {
+	containers, err := p.ListContainers(rootFull)
+	if err != nil {
+		return nil, err
+	}
+	for _, c := range containers {
+		if c.Name == name {
+			return &c, nil
+		}
+	}
+	return nil, errors.New("container not found")
+}
+
+// execArgs builds the podman exec arguments running a command in the
+// container, as root if the command starts with sudo.
+func (p *PodmanBackend) execArgs(name string, interactive bool, args []string) []string {
+	execArgs := []string{"exec", "--interactive"}
+	if interactive && isTerminal() {
+		execArgs = append(execArgs, "--tty")
+	}
+
+	if len(args) > 0 && args[0] == "sudo" {
+		execArgs = append(execArgs, "--user", "root")
+		args = args[1:]
+	}
+
+	if workDir, err := os.Getwd(); err == nil {
+		if userHome, err := os.UserHomeDir(); err == nil && strings.HasPrefix(workDir, userHome) {
+			execArgs = append(execArgs, "--workdir", workDir)
+		}
+	}
+
+	execArgs = append(execArgs, name)
+	return append(execArgs, args...)
+}
+
+// isTerminal informs whether the standard input is a terminal.
+func isTerminal() bool {
+	info, err := os.Stdin.Stat()
+	return err == nil && info.Mode()&os.ModeCharDevice != 0
+}
+
+func (p *PodmanBackend) ContainerExec(name string, captureOutput, muteOutput, rootFull, detached bool, args ...string) (string, error) {
+	err := p.ContainerStart(name, rootFull)
+	if err != nil {
+		return "", err
+	}
+
+	out, err := p.run(p.execArgs(name, !captureOutput && !detached, args), captureOutput, muteOutput, rootFull, detached, false)
+	return string(out), err
+}
+
+func (p *PodmanBackend) ContainerQuery(name string, rootFull bool, args ...string) (string, error) {
+	_, err := p.run([]string{"start", name}, true, true, rootFull, false, true)
+	if err != nil {
+		return "", err
+	}
+
+	out, err := p.run(p.execArgs(name, false, args), true, false, rootFull, false, true)
+	return string(out), err
+}
+
+func (p *PodmanBackend) ContainerEnter(name string, rootFull bool) error {
+	err := p.ContainerStart(name, rootFull)
+	if err != nil {
+		return err
+	}
+
+	shell := "command -v bash >/dev/null 2>&1 && exec bash -l || exec sh -l"
+	_, err = p.run(p.execArgs(name, true, []string{"sh", "-c", shell}), false, false, rootFull, false, false)
+	if err != nil && err.Error() == "exit status 130" {
+		return nil
+	}
+	return err
+}
+
+func (p *PodmanBackend) ContainerStart(name string, rootFull bool) error {
+	_, err := p.run([]string{"start", name}, true, true, rootFull, false, false)
+	return err
+}
+
+func (p *PodmanBackend) ContainerStop(name string, rootFull bool) error {
+	_, err := p.run([]string{"stop", name}, false, false, rootFull, false, false)
+	return err
+}
+
+func (p *PodmanBackend) ContainerDelete(name string, rootFull bool) error {
+	_, err := p.run([]string{"rm", "--force", name}, false, true, rootFull, false, false)
+	return err
+}
+
+func (p *PodmanBackend) ContainerCommit(name, image string, rootFull bool) error {
+	_, err := p.run([]string{"commit", name, image}, false, true, rootFull, false, false)
+	return err
+}
+
+func (p *PodmanBackend) ImageDelete(image string, rootFull bool) error {
+	_, err := p.run([]string{"rmi", "--force", image}, false, true, rootFull, false, false)
+	return err
+}
+
+// engineCommand returns the command line running podman, as written in
+// exported launchers.
+func (p *PodmanBackend) engineCommand(rootFull bool) string {
+	if rootFull {
+		return "pkexec " + p.EngineBinary
+	}
+	return p.EngineBinary
+}
+
+// ContainerExportDesktopEntry exports the desktop entries of the container
+// whose file name contains the application name, launching it through
+// podman exec. Icons are not exported.
+func (p *PodmanBackend) ContainerExportDesktopEntry(name, app, label string, rootFull bool) error {
+	out, err := p.ContainerQuery(name, rootFull, "ls", "-1", "/usr/share/applications")
+	if err != nil {
+		return err
+	}
+
+	appsDir, err := hostApplicationsDir()
+	if err != nil {
+		return err
+	}
+
+	exported := 0
+	for _, file := range strings.Split(out, "\n") {
+		file = strings.TrimSpace(file)
+		if filepath.Ext(file) != ".desktop" || !strings.Contains(strings.ToLower(file), strings.ToLower(app)) {
+			continue
+		}
+
+		content, err := p.ContainerQuery(name, rootFull, "cat", filepath.Join("/usr/share/applications", file))
+		if err != nil {
+			return err
+		}
+
+		hostFile := filepath.Join(appsDir, name+"-"+file)
+		if IsDryRun() {
+			printDryRun("write %s", hostFile)
+			exported++
+			continue
+		}
+
+		err = os.MkdirAll(appsDir, 0755)
+		if err != nil {
+			return err
+		}
+
+		err = os.WriteFile(hostFile, []byte(p.rewriteDesktopEntry(content, name, label, rootFull)), 0644)
+		if err != nil {
+			return err
+		}
+		exported++
+	}
+
+	if exported == 0 {
+		return fmt.Errorf("no desktop entry found for %s", app)
+	}
+
+	return nil
+}
+
+// rewriteDesktopEntry makes a container desktop entry launch the
+// application through podman exec and labels its name. The container is
+// started first, podman exec fails on a stopped one, e.g. after a reboot.
+func (p *PodmanBackend) rewriteDesktopEntry(content, name, label string, rootFull bool) string {
+	start := fmt.Sprintf(`%s start %s >/dev/null 2>&1; exec "$0" "$@"`, p.engineCommand(rootFull), name)
+
+	lines := make([]string, 0)
+	for _, line := range strings.Split(content, "\n") {
+		switch {
+		case strings.HasPrefix(line, "TryExec="):
+			continue
+		case strings.HasPrefix(line, "Exec="):
+			line = fmt.Sprintf("Exec=sh -c %s %s exec %s %s", quoteDesktopExecArg(start), p.engineCommand(rootFull), name, strings.TrimPrefix(line, "Exec="))
+		case strings.HasPrefix(line, "Name=") || strings.HasPrefix(line, "Name["):
+			line = fmt.Sprintf("%s (%s)", line, label)
+		}
+		lines = append(lines, line)
+	}
+
+	return strings.Join(lines, "\n")
+}
+
+// quoteDesktopExecArg quotes an argument of a desktop entry Exec key: the
+// reserved characters are escaped inside double quotes, then every
+// backslash is escaped again as the key is a string value.
+func quoteDesktopExecArg(arg string) string {
+	quoted := strings.NewReplacer(`\`, `\\`, `"`, `\"`, "`", "\\`", `$`, `\$`).Replace(arg)
+	return `"` + strings.ReplaceAll(quoted, `\`, `\\`) + `"`
+}
+
+func (p *PodmanBackend) ContainerUnexportDesktopEntry(name, app string, rootFull bool) error {
+	files, err := exportedDesktopFiles(storageName(name, rootFull), app)
+	if err != nil {
+		return err
+	}
+
+	_, err = removeHostFiles(files)
+	return err
+}
+
+// ContainerExportBin writes a launcher running the binary in the container.
+// Like distrobox-export, its header names the container, so it can be found
+// again by findHostArtefacts.
+func (p *PodmanBackend) ContainerExportBin(name, binary, path string, rootFull bool) error {
+	launcher := filepath.Join(path, filepath.Base(binary))
+	if IsDryRun() {
+		printDryRun("write %s", launcher)
+		return nil
+	}
+
+	script := fmt.Sprintf(`#!/bin/sh
+# abg_binary
+# name: %s
+%s start %s >/dev/null 2>&1
+if [ -t 0 ] && [ -t 1 ]; then
+	exec %s exec --interactive --tty %s %s "$@"
+fi
+exec %s exec --interactive %s %s "$@"
+`, name, p.engineCommand(rootFull), name,
+		p.engineCommand(rootFull), name, binary,
+		p.engineCommand(rootFull), name, binary)
+
+	err := os.MkdirAll(path, 0755)
+	if err != nil {
+		return err
+	}
+
+	return os.WriteFile(launcher, []byte(script), 0755)
+}
+
+// ContainerUnexportBin removes the launcher of the binary from the default
+// bin directory, if it launches this container.
+func (p *PodmanBackend) ContainerUnexportBin(name, binary string, rootFull bool) error {
+	binDir, err := hostBinDir()
+	if err != nil {
+		return err
+	}
+
+	launcher := filepath.Join(binDir, filepath.Base(binary))
+	content, err := os.ReadFile(launcher)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil
+		}
+		return err
+	}
+
+	if !strings.Contains(string(content), "# abg_binary") || !strings.Contains(string(content), "# name: "+name+"\n") {
+		return nil
+	}
+
+	_, err = removeHostFiles([]string{launcher})
+	return err
+}
@@ -0,0 +1,63 @@
+package core
+
+import (
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+)
+
+func TestPodmanCreateArgsInit(t *testing.T) {
+	setupTestAbg(t)
+
+	p := &PodmanBackend{EngineBinary: "/usr/bin/podman"}
+
+	args, err := p.createArgs("abg-dev", "docker.io/library/fedora:40", "", nil, true, false, true, false, "dev", ContainerExtras{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := []string{"--user", "root", "--systemd", "always", "docker.io/library/fedora:40", "/sbin/init"}
+	if len(args) < len(want) || !slices.Equal(args[len(args)-len(want):], want) {
+		t.Errorf("args = %v, should end with %v", args, want)
+	}
+	if !slices.Contains(args, "keep-id") {
+		t.Errorf("args = %v, the user should still be mapped", args)
+	}
+
+	args, err = p.createArgs("abg-dev", "docker.io/library/fedora:40", "", nil, false, false, true, false, "dev", ContainerExtras{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if slices.Contains(args, "--user") {
+		t.Errorf("args = %v, containers without init run as the user", args)
+	}
+}
+
+func TestPodmanRunReportsStderr(t *testing.T) {
+	setupTestAbg(t)
+
+	engine := filepath.Join(t.TempDir(), "podman")
+	err := os.WriteFile(engine, []byte("#!/bin/sh\necho out\necho 'E: broken' >&2\nexit 100\n"), 0755)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	p := &PodmanBackend{EngineBinary: engine}
+	out, err := p.run([]string{"exec", "abg-dev", "apt", "upgrade"}, true, true, false, false, false)
+	if err == nil || err.Error() != "E: broken\n" {
+		t.Errorf("err = %v, want the stderr output", err)
+	}
+	if string(out) != "out\n" {
+		t.Errorf("output = %q", out)
+	}
+
+	err = os.WriteFile(engine, []byte("#!/bin/sh\nexit 3\n"), 0755)
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = p.run([]string{"exec", "abg-dev", "false"}, true, true, false, false, false)
+	if err == nil || err.Error() != "exit status 3" {
+		t.Errorf("err = %v, want the exit status without stderr output", err)
+	}
+}
@@ -0,0 +1,135 @@
+package core
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"sort"
+	"strings"
+)
+
+// DefaultSecurityProfile is used by subsystems and stacks without profile,
+// it keeps the usual distrobox integration with the host.
+const DefaultSecurityProfile = "host-integrated"
+
+// Network modes of a security profile.
+const (
+	NetworkHost    = "host"    // The host network is shared
+	NetworkPrivate = "private" // The subsystem has its own network namespace
+	NetworkNone    = "none"    // The subsystem has no network at all
+)
+
+// SecurityProfile defines how much of the host a subsystem can access.
+type SecurityProfile struct {
+	Name         string
+	Description  string
+	IsolateHome  bool     // Own home instead of the user one, podman backend only
+	Network      string   // One of the Network* modes
+	AllowDevices bool     // Device mounts and GPU integration are allowed
+	CapDrop      []string // Capabilities dropped from the container
+	// ReadOnlyRoot mounts the container root filesystem read-only, nothing
+	// can be installed after the creation. A subsystem needing a setup is
+	// set up writable, then recreated read-only from a committed image.
+	ReadOnlyRoot bool
+}
+
+// securityProfiles are the built-in security profiles.
+var securityProfiles = map[string]*SecurityProfile{
+	"host-integrated": {
+		Name:         "host-integrated",
+		Description:  "Shares the user home, the host network and devices",
+		Network:      NetworkHost,
+		AllowDevices: true,
+	},
+	"dev": {
+		Name:         "dev",
+		Description:  "Own home, shares the host network and devices (podman backend)",
+		IsolateHome:  true,
+		Network:      NetworkHost,
+		AllowDevices: true,
+		CapDrop:      []string{"SYS_ADMIN", "SYS_MODULE", "SYS_RAWIO"},
+	},
+	"strict": {
+		Name:         "strict",
+		Description:  "Own home and network, no devices, read-only root (podman backend)",
+		IsolateHome:  true,
+		Network:      NetworkPrivate,
+		CapDrop:      []string{"AUDIT_WRITE", "MKNOD", "NET_ADMIN", "NET_RAW", "SYS_ADMIN", "SYS_MODULE", "SYS_PTRACE", "SYS_RAWIO"},
+		ReadOnlyRoot: true,
+	},
+}
+
+// LoadSecurityProfile returns a security profile by name, the default one if
+// the name is empty.
+func LoadSecurityProfile(name string) (*SecurityProfile, error) {
+	if name == "" {
+		name = DefaultSecurityProfile
+	}
+
+	profile, ok := securityProfiles[name]
+	if !ok {
+		names := make([]string, 0, len(securityProfiles))
+		for _, p := range ListSecurityProfiles() {
+			names = append(names, p.Name)
+		}
+		return nil, fmt.Errorf("unknown security profile %q, available profiles: %s", name, strings.Join(names, ", "))
+	}
+
+	return profile, nil
+}
+
+// ListSecurityProfiles returns the security profiles sorted by name.
+func ListSecurityProfiles() []*SecurityProfile {
+	profiles := make([]*SecurityProfile, 0, len(securityProfiles))
+	for _, profile := range securityProfiles {
+		profiles = append(profiles, profile)
+	}
+
+	sort.Slice(profiles, func(i, j int) bool { return profiles[i].Name < profiles[j].Name })
+	return profiles
+}
+
+// SecurityProfile returns the security profile of the subsystem: its own,
+// else the one of its stack, else the default one.
+func (s *SubSystem) SecurityProfile() (*SecurityProfile, error) {
+	if s.Profile != "" {
+		return LoadSecurityProfile(s.Profile)
+	}
+	return LoadSecurityProfile(s.Stack.Profile)
+}
+
+// privateHomePath returns the home of a subsystem whose profile isolates it.
+func privateHomePath(internalName string) string {
+	return filepath.Join(abg.Cnf.AbgStoragePath, "homes", internalName)
+}
+
+// apply checks the subsystem against the profile and sets the container
+// options enforcing it. It returns the home and the GPU integration to
+// create the container with.
+func (p *SecurityProfile) apply(s *SubSystem, mounts []Mount, extras *ContainerExtras) (string, bool, error) {
+	if !p.AllowDevices && slices.ContainsFunc(mounts, func(m Mount) bool { return m.kind() == MountTypeDevice }) {
+		return "", false, fmt.Errorf("security profile %s doesn't allow devices", p.Name)
+	}
+
+	home := s.Home
+	if p.IsolateHome {
+		if home == "" {
+			home = privateHomePath(s.InternalName)
+		}
+
+		if !IsDryRun() {
+			err := os.MkdirAll(home, 0700)
+			if err != nil {
+				return "", false, err
+			}
+		}
+	}
+
+	extras.IsolateHome = p.IsolateHome
+	extras.Network = p.Network
+	extras.CapDrop = p.CapDrop
+	extras.ReadOnlyRoot = p.ReadOnlyRoot
+
+	return home, s.HasNvidiaIntegration && p.AllowDevices, nil
+}
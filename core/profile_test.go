@@ -0,0 +1,139 @@
+package core
+
+import (
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+)
+
+func TestSecurityProfiles(t *testing.T) {
+	fake := setupTestAbg(t)
+
+	writeTestFile(t, filepath.Join(abg.Cnf.UserStacksPath, "ubuntu.yaml"), `
+name: ubuntu
+base: docker.io/library/ubuntu:22.04
+pkgmanager: apt
+`)
+	writeTestFile(t, filepath.Join(abg.Cnf.UserStacksPath, "dev.yaml"), `
+name: dev
+extends: ubuntu
+profile: dev
+`)
+
+	create := func(name, stackName, profile string, mounts ...Mount) (FakeCall, error) {
+		t.Helper()
+
+		stack, err := LoadStack(stackName)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		subSystem := &SubSystem{InternalName: genInternalName(name), Name: name, Stack: stack, Profile: profile, Mounts: mounts, HasNvidiaIntegration: true}
+		err = subSystem.Create()
+		if err != nil {
+			return FakeCall{}, err
+		}
+
+		creates := fake.CallsTo("create")
+		return creates[len(creates)-1], nil
+	}
+
+	call, err := create("plain", "ubuntu", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if call.Home != "" || call.Extras.IsolateHome || call.Extras.Network != NetworkHost || len(call.Extras.CapDrop) != 0 {
+		t.Errorf("default profile should keep the host integration: %+v", call)
+	}
+
+	call, err = create("work", "dev", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if call.Home != privateHomePath(genInternalName("work")) || !call.Extras.IsolateHome || len(call.Extras.CapDrop) == 0 {
+		t.Errorf("stack profile should isolate the home: %+v", call)
+	}
+	if _, err := os.Stat(call.Home); err != nil {
+		t.Errorf("private home was not created: %s", err)
+	}
+
+	call, err = create("locked", "dev", "strict")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !call.Extras.ReadOnlyRoot || call.Extras.Network != NetworkPrivate {
+		t.Errorf("subsystem profile should override the stack one: %+v", call.Extras)
+	}
+
+	loaded, err := LoadSubSystem("locked", false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if profile, err := loaded.SecurityProfile(); err != nil || profile.Name != "strict" {
+		t.Errorf("profile was not persisted: %v, %v", profile, err)
+	}
+
+	_, err = create("gpu", "ubuntu", "strict", Mount{Type: MountTypeDevice, Source: "/dev/kvm"})
+	if err == nil {
+		t.Error("strict profile should reject devices")
+	}
+
+	_, err = create("unknown", "ubuntu", "paranoid")
+	if err == nil {
+		t.Error("unknown profiles should be rejected")
+	}
+}
+
+func TestReadOnlyRootSealedAfterSetup(t *testing.T) {
+	fake := setupTestAbg(t)
+	fake.NoCreatePackages = true
+	writeTestPkgManager(t)
+
+	stack := &Stack{Name: "ubuntu", Base: "ubuntu:22.04", PkgManager: "apt", Packages: []string{"htop"}, PostCreate: []string{"touch /etc/ready"}}
+	subSystem := &SubSystem{InternalName: genInternalName("dev"), Name: "dev", Stack: stack, Profile: "strict"}
+
+	err := subSystem.Create()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	image := sealedImageName(subSystem.InternalName)
+	var methods []string
+	for _, call := range fake.Calls {
+		if call.Method != "list" {
+			methods = append(methods, call.Method)
+		}
+	}
+	want := []string{"create", "exec", "exec", "exec", "commit", "delete", "create"}
+	if !slices.Equal(methods, want) {
+		t.Fatalf("calls = %v, want %v", methods, want)
+	}
+
+	creates := fake.CallsTo("create")
+	if creates[0].Extras.ReadOnlyRoot {
+		t.Error("the root should be writable during the setup")
+	}
+	if !creates[1].Extras.ReadOnlyRoot || !creates[1].Extras.IsolateHome {
+		t.Errorf("the sealed container should keep the profile: %+v", creates[1].Extras)
+	}
+	if commits := fake.CallsTo("commit"); commits[0].Args[0] != image {
+		t.Errorf("committed to %v, want %s", commits[0].Args, image)
+	}
+
+	loaded, err := LoadSubSystem("dev", false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if loaded.Image != image {
+		t.Errorf("image = %q, want %q", loaded.Image, image)
+	}
+
+	_, err = loaded.Remove()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if rmis := fake.CallsTo("rmi"); len(rmis) != 1 || rmis[0].Args[0] != image {
+		t.Errorf("the sealed image should be removed with the subsystem: %+v", rmis)
+	}
+}
@@ -0,0 +1,376 @@
+package core
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"time"
+
+	"gopkg.in/yaml.v2"
+)
+
+// defaultRecipePrefix is the install prefix used when a recipe does not set one.
+const defaultRecipePrefix = "/usr/local"
+
+// Recipe represents a set of instructions to build and install software from
+// source inside a subsystem.
+type Recipe struct {
+	Name       string
+	Version    string
+	Source     string   // Git repository or archive URL, fetched when Fetch is empty
+	SourceType string   // Either git or archive, guessed from Source when empty
+	Packages   []string // Build dependencies installed with the subsystem package manager
+	Prefix     string
+	Fetch      []string
+	Configure  []string
+	Build      []string
+	Install    []string
+	Binaries   []string // Binaries exported to the host after the install step
+}
+
+// BuildRecord stores what a recipe installed in a subsystem.
+type BuildRecord struct {
+	Recipe    string
+	Version   string
+	Source    string
+	Prefix    string
+	Files     []string // Files the install step added or changed under the prefix
+	Binaries  []string // Installed files directly under the prefix bin directory
+	Exported  []string
+	BuiltAt   time.Time
+	SubSystem string
+}
+
+// LoadRecipe loads a recipe by name, user recipes take precedence over the
+// built-in ones.
+func LoadRecipe(name string) (*Recipe, error) {
+	usrRecipeFile := SelectYamlFile(abg.Cnf.UserRecipesPath, name)
+	recipe, err := LoadRecipeFromPath(usrRecipeFile)
+	if err != nil {
+		recipeFile := SelectYamlFile(abg.Cnf.RecipesPath, name)
+		recipe, err = LoadRecipeFromPath(recipeFile)
+	}
+	return recipe, err
+}
+
+// LoadRecipeFromPath loads a recipe from the specified path.
+func LoadRecipeFromPath(path string) (*Recipe, error) {
+	recipe := &Recipe{}
+
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, errors.New("recipe not found")
+	}
+	defer f.Close()
+
+	data, err := io.ReadAll(f)
+	if err != nil {
+		return nil, err
+	}
+
+	err = yaml.Unmarshal(data, recipe)
+	if err != nil {
+		return nil, err
+	}
+
+	if recipe.Name == "" || len(recipe.Install) == 0 {
+		return nil, errors.New("invalid recipe file")
+	}
+
+	if recipe.Source == "" && len(recipe.Fetch) == 0 {
+		return nil, errors.New("invalid recipe file: no source to fetch")
+	}
+
+	if recipe.SourceType != "" && recipe.SourceType != "git" && recipe.SourceType != "archive" {
+		return nil, fmt.Errorf("invalid recipe file: unknown source type %s", recipe.SourceType)
+	}
+
+	if recipe.Prefix == "" {
+		recipe.Prefix = defaultRecipePrefix
+	}
+
+	return recipe, nil
+}
+
+// ListRecipes returns a list of all recipes.
+func ListRecipes() []*Recipe {
+	recipes := listRecipesFromPath(abg.Cnf.UserRecipesPath)
+
+	if abg.Cnf.UserRecipesPath == abg.Cnf.RecipesPath {
+		// user install
+		return recipes
+	}
+
+	return append(recipes, listRecipesFromPath(abg.Cnf.RecipesPath)...)
+}
+
+// listRecipesFromPath returns a list of recipes from the specified path.
+func listRecipesFromPath(path string) []*Recipe {
+	recipes := make([]*Recipe, 0)
+
+	files, err := os.ReadDir(path)
+	if err != nil {
+		return recipes
+	}
+
+	for _, file := range files {
+		extension := filepath.Ext(file.Name())
+		if !file.IsDir() && (extension == ".yaml" || extension == ".yml") {
+			recipe, err := LoadRecipeFromPath(filepath.Join(path, file.Name()))
+			if err == nil {
+				recipes = append(recipes, recipe)
+			}
+		}
+	}
+
+	return recipes
+}
+
+// buildDir creates the directory the recipe is built in. It is made inside
+// the subsystem, whose home may not be the host one and which may run as
+// root, so nothing is shared with the host.
+func (r *Recipe) buildDir(subSystem *SubSystem) (string, error) {
+	template := "/tmp/abg-build.XXXXXX"
+	out, err := subSystem.Exec(true, false, "mktemp", "-d", template)
+	if err != nil {
+		return "", fmt.Errorf("failed to create the build directory: %w", err)
+	}
+
+	// Nothing was created, the template stands for the directory
+	if IsDryRun() {
+		return template, nil
+	}
+
+	dir := strings.TrimSpace(out)
+	if !strings.HasPrefix(dir, "/") {
+		return "", fmt.Errorf("failed to create the build directory: unexpected mktemp output %q", out)
+	}
+
+	return dir, nil
+}
+
+// gitHosts are the hosts whose URLs are git repositories, unless they point
+// at an archive.
+var gitHosts = []string{"github.com", "gitlab.com", "codeberg.org", "bitbucket.org", "git.sr.ht"}
+
+// archiveExtensions are the extensions of the archives fetched with curl.
+var archiveExtensions = []string{".tar", ".tar.gz", ".tgz", ".tar.bz2", ".tbz2", ".tar.xz", ".txz", ".tar.zst"}
+
+// isGitSource informs whether the recipe source is a git repository, as
+// declared by SourceType or guessed from the URL.
+func (r *Recipe) isGitSource() bool {
+	if r.SourceType != "" {
+		return r.SourceType == "git"
+	}
+
+	source := strings.TrimSuffix(r.Source, "/")
+	if strings.HasSuffix(source, ".git") || strings.HasPrefix(source, "git@") || strings.HasPrefix(source, "git://") {
+		return true
+	}
+
+	for _, ext := range archiveExtensions {
+		if strings.HasSuffix(source, ext) {
+			return false
+		}
+	}
+
+	host := strings.TrimPrefix(strings.TrimPrefix(source, "https://"), "http://")
+	host, _, _ = strings.Cut(host, "/")
+	return slices.Contains(gitHosts, host)
+}
+
+// fetchSteps returns the fetch steps, deriving them from Source when the
+// recipe does not declare any.
+func (r *Recipe) fetchSteps() []string {
+	if len(r.Fetch) > 0 {
+		return r.Fetch
+	}
+
+	if r.isGitSource() {
+		return []string{fmt.Sprintf("git clone --depth 1 %q src", r.Source)}
+	}
+
+	return []string{fmt.Sprintf("mkdir -p src && curl -fsSL %q | tar -xa --strip-components=1 -C src", r.Source)}
+}
+
+// Run runs the fetch, configure, build and install steps of the recipe in
+// the given subsystem, then exports the declared binaries unless noExport
+// is set. The resulting BuildRecord, listing the files the install step
+// wrote under the prefix, is saved in the ABG storage.
+func (r *Recipe) Run(subSystem *SubSystem, noExport bool) (*BuildRecord, error) {
+	if len(r.Packages) > 0 {
+		pkgManager, err := subSystem.Stack.GetPkgManager()
+		if err != nil {
+			return nil, err
+		}
+
+		_, err = subSystem.Exec(false, false, pkgManager.GenCmd(pkgManager.CmdInstall, r.Packages...)...)
+		if err != nil {
+			return nil, fmt.Errorf("failed to install build dependencies: %w", err)
+		}
+	}
+
+	workDir, err := r.buildDir(subSystem)
+	if err != nil {
+		return nil, err
+	}
+	defer subSystem.Exec(false, false, "rm", "-rf", workDir)
+
+	stages := []struct {
+		name  string
+		dir   string
+		steps []string
+	}{
+		{"fetch", workDir, r.fetchSteps()},
+		{"configure", filepath.Join(workDir, "src"), r.Configure},
+		{"build", filepath.Join(workDir, "src"), r.Build},
+		{"install", filepath.Join(workDir, "src"), r.Install},
+	}
+
+	// Installed files are found by their change time, which install tools
+	// can't preserve unlike the modification time
+	stamp := filepath.Join(workDir, "install.stamp")
+
+	for _, stage := range stages {
+		if stage.name == "install" {
+			_, err = subSystem.Exec(false, false, "touch", stamp)
+			if err != nil {
+				return nil, err
+			}
+		}
+
+		for _, step := range stage.steps {
+			script := fmt.Sprintf("cd %q && export PREFIX=%q && %s", stage.dir, r.Prefix, step)
+			_, err := subSystem.Exec(false, false, "sh", "-c", script)
+			if err != nil {
+				return nil, fmt.Errorf("%s step %q failed: %w", stage.name, step, err)
+			}
+		}
+	}
+
+	record := &BuildRecord{
+		Recipe:    r.Name,
+		Version:   r.Version,
+		Source:    r.Source,
+		Prefix:    r.Prefix,
+		Files:     []string{},
+		Binaries:  []string{},
+		Exported:  []string{},
+		BuiltAt:   time.Now(),
+		SubSystem: subSystem.Name,
+	}
+
+	if !IsDryRun() {
+		record.Files, err = r.installedFiles(subSystem, stamp)
+		if err != nil {
+			return nil, fmt.Errorf("failed to list the installed files: %w", err)
+		}
+
+		binDir := filepath.Join(r.Prefix, "bin")
+		for _, file := range record.Files {
+			if filepath.Dir(file) == binDir {
+				record.Binaries = append(record.Binaries, filepath.Base(file))
+			}
+		}
+	}
+
+	if !noExport {
+		for _, binary := range r.Binaries {
+			if !strings.HasPrefix(binary, "/") {
+				binary = filepath.Join(r.Prefix, "bin", binary)
+			}
+
+			err := subSystem.ExportBin(binary, "")
+			if err != nil {
+				return record, fmt.Errorf("failed to export %s: %w", binary, err)
+			}
+			record.Exported = append(record.Exported, binary)
+		}
+	}
+
+	return record, SaveBuildRecord(subSystem, record)
+}
+
+// installedFiles returns the files and links under the recipe prefix changed
+// after the stamp was written.
+func (r *Recipe) installedFiles(subSystem *SubSystem, stamp string) ([]string, error) {
+	out, err := subSystem.Query("find", r.Prefix, "-cnewer", stamp, "(", "-type", "f", "-o", "-type", "l", ")", "-print")
+	if err != nil {
+		return nil, err
+	}
+
+	files := make([]string, 0)
+	for _, line := range strings.Split(out, "\n") {
+		line = strings.TrimSpace(line)
+		if line != "" {
+			files = append(files, line)
+		}
+	}
+	slices.Sort(files)
+
+	return files, nil
+}
+
+// buildRecordsPath returns the path of the build records file of a subsystem.
+func buildRecordsPath(subSystem *SubSystem) string {
+	return filepath.Join(abg.Cnf.AbgStoragePath, "builds", storageName(subSystem.InternalName, subSystem.IsRootfull)+".json")
+}
+
+// ListBuildRecords returns the builds recorded for a subsystem.
+func ListBuildRecords(subSystem *SubSystem) ([]*BuildRecord, error) {
+	records := make([]*BuildRecord, 0)
+
+	data, err := os.ReadFile(buildRecordsPath(subSystem))
+	if err != nil {
+		if os.IsNotExist(err) {
+			return records, nil
+		}
+		return nil, err
+	}
+
+	err = json.Unmarshal(data, &records)
+	if err != nil {
+		return nil, err
+	}
+
+	return records, nil
+}
+
+// SaveBuildRecord stores a build record, replacing any previous build of the
+// same recipe in the subsystem.
+func SaveBuildRecord(subSystem *SubSystem, record *BuildRecord) error {
+	if IsDryRun() {
+		return nil
+	}
+
+	records, err := ListBuildRecords(subSystem)
+	if err != nil {
+		return err
+	}
+
+	newRecords := make([]*BuildRecord, 0, len(records)+1)
+	for _, r := range records {
+		if r.Recipe != record.Recipe {
+			newRecords = append(newRecords, r)
+		}
+	}
+	newRecords = append(newRecords, record)
+
+	data, err := json.MarshalIndent(newRecords, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	path := buildRecordsPath(subSystem)
+	err = os.MkdirAll(filepath.Dir(path), 0755)
+	if err != nil {
+		return err
+	}
+
+	return os.WriteFile(path, data, 0644)
+}
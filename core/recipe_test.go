@@ -0,0 +1,135 @@
+package core
+
+import (
+	"errors"
+	"fmt"
+	"path/filepath"
+	"slices"
+	"testing"
+)
+
+// testBuildDir is the build directory mktemp creates in the tests.
+const testBuildDir = "/tmp/abg-build.Xq3c9A"
+
+func newTestRecipe(t *testing.T, fake *FakeBackend) (*Recipe, *SubSystem, string) {
+	t.Helper()
+
+	recipe := &Recipe{
+		Name:     "hello",
+		Source:   "https://example.com/hello.git",
+		Prefix:   defaultRecipePrefix,
+		Build:    []string{"make"},
+		Install:  []string{"make install"},
+		Binaries: []string{"hello"},
+	}
+	subSystem := &SubSystem{InternalName: genInternalName("dev"), Name: "dev", Stack: &Stack{Name: "ubuntu", PkgManager: "apt"}}
+
+	fake.Outputs["mktemp -d /tmp/abg-build.XXXXXX"] = testBuildDir + "\n"
+
+	return recipe, subSystem, testBuildDir
+}
+
+func TestRecipeRunFailingStep(t *testing.T) {
+	fake := setupTestAbg(t)
+	recipe, subSystem, workDir := newTestRecipe(t, fake)
+
+	script := fmt.Sprintf("sh -c cd %q && export PREFIX=%q && make", filepath.Join(workDir, "src"), recipe.Prefix)
+	fake.Errors[script] = errors.New("exit status 2")
+
+	_, err := recipe.Run(subSystem, true)
+	if err == nil {
+		t.Fatal("a failing build step should fail the build")
+	}
+
+	for _, call := range fake.CallsTo("exec") {
+		if slices.Contains(call.Args, "make install") {
+			t.Error("the install step should not run after a failed build")
+		}
+	}
+
+	records, err := ListBuildRecords(subSystem)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(records) != 0 {
+		t.Errorf("a failed build should not be recorded: %+v", records)
+	}
+
+	execs := fake.CallsTo("exec")
+	if last := execs[len(execs)-1]; !slices.Equal(last.Args, []string{"rm", "-rf", workDir}) {
+		t.Errorf("the build directory should be removed after a failed build, last command %v", last.Args)
+	}
+}
+
+func TestRecipeRunRecordsInstalledFiles(t *testing.T) {
+	fake := setupTestAbg(t)
+	recipe, subSystem, workDir := newTestRecipe(t, fake)
+
+	stamp := filepath.Join(workDir, "install.stamp")
+	fake.Outputs["find /usr/local -cnewer "+stamp+" ( -type f -o -type l ) -print"] = "/usr/local/share/man/man1/hello.1\n/usr/local/bin/hello\n/usr/local/bin/hello-helper\n"
+
+	record, err := recipe.Run(subSystem, true)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	wantFiles := []string{"/usr/local/bin/hello", "/usr/local/bin/hello-helper", "/usr/local/share/man/man1/hello.1"}
+	if !slices.Equal(record.Files, wantFiles) {
+		t.Errorf("files = %v, want %v", record.Files, wantFiles)
+	}
+	if !slices.Equal(record.Binaries, []string{"hello", "hello-helper"}) {
+		t.Errorf("binaries = %v, want the installed ones", record.Binaries)
+	}
+
+	records, err := ListBuildRecords(subSystem)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(records) != 1 || !slices.Equal(records[0].Files, wantFiles) {
+		t.Errorf("unexpected saved records: %+v", records)
+	}
+}
+
+func TestRecipeRunDryRun(t *testing.T) {
+	fake := setupTestAbg(t)
+	recipe, subSystem, _ := newTestRecipe(t, fake)
+	// mktemp prints nothing as it does not run
+	delete(fake.Outputs, "mktemp -d /tmp/abg-build.XXXXXX")
+
+	SetDryRun(true)
+	t.Cleanup(func() { SetDryRun(false) })
+
+	_, err := recipe.Run(subSystem, true)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	script := fmt.Sprintf("cd %q && export PREFIX=%q && make", "/tmp/abg-build.XXXXXX/src", recipe.Prefix)
+	found := false
+	for _, call := range fake.CallsTo("exec") {
+		found = found || slices.Contains(call.Args, script)
+	}
+	if !found {
+		t.Error("the steps should show the build directory template")
+	}
+}
+
+func TestRecipeFetchSteps(t *testing.T) {
+	tests := []struct {
+		recipe Recipe
+		want   string
+	}{
+		{Recipe{Source: "https://example.com/hello.git"}, `git clone --depth 1 "https://example.com/hello.git" src`},
+		{Recipe{Source: "https://github.com/example/hello"}, `git clone --depth 1 "https://github.com/example/hello" src`},
+		{Recipe{Source: "https://git.example.com/hello", SourceType: "git"}, `git clone --depth 1 "https://git.example.com/hello" src`},
+		{Recipe{Source: "https://github.com/example/hello/archive/refs/tags/v1.0.tar.gz"}, `mkdir -p src && curl -fsSL "https://github.com/example/hello/archive/refs/tags/v1.0.tar.gz" | tar -xa --strip-components=1 -C src`},
+		{Recipe{Source: "https://example.com/download?file=hello v1&sig=a;b", SourceType: "archive"}, `mkdir -p src && curl -fsSL "https://example.com/download?file=hello v1&sig=a;b" | tar -xa --strip-components=1 -C src`},
+	}
+
+	for _, test := range tests {
+		got := test.recipe.fetchSteps()
+		if len(got) != 1 || got[0] != test.want {
+			t.Errorf("fetch steps of %s = %v, want %q", test.recipe.Source, got, test.want)
+		}
+	}
+}
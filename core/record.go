@@ -0,0 +1,220 @@
+package core
+
+import (
+	"encoding/json"
+	"log"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// SubSystemRecord is the persisted state of a subsystem. Container labels
+// are fixed at creation and lost with the container, the record is not, so
+// a subsystem can be recreated from it. Exports and the packages installed
+// by the user are kept next to it, in the export registry and the tracking.
+type SubSystemRecord struct {
+	Name                 string
+	Stack                *Stack // The stack as it was when the subsystem was created
+	HasInit              bool
+	IsManaged            bool
+	IsRootfull           bool
+	IsUnshared           bool
+	HasNvidiaIntegration bool
+	Home                 string
+	Hostname             string
+	Mounts               []Mount
+	Profile              string
+	Image                string // Set when the container was created from a sealed image
+	Env                  []string
+	EngineFlags          []string
+	CreatedAt            time.Time
+}
+
+// storageName returns the name the state of a subsystem is stored under.
+// Rootless and rootful containers don't share a namespace, so the state of
+// rootful subsystems is kept apart, in root directories.
+func storageName(internalName string, isRootFull bool) string {
+	if isRootFull {
+		return filepath.Join("root", internalName)
+	}
+	return internalName
+}
+
+// recordsDir returns the directory of the records of the rootless or the
+// rootful subsystems.
+func recordsDir(isRootFull bool) string {
+	return filepath.Join(abg.Cnf.AbgStoragePath, "subsystems", storageName("", isRootFull))
+}
+
+// recordPath returns the path of the record of a subsystem.
+func recordPath(internalName string, isRootFull bool) string {
+	return filepath.Join(recordsDir(isRootFull), internalName+".json")
+}
+
+// loadRecord loads the record of a subsystem, nil is returned if there is
+// none, as for subsystems created before records were introduced.
+func loadRecord(internalName string, isRootFull bool) (*SubSystemRecord, error) {
+	data, err := os.ReadFile(recordPath(internalName, isRootFull))
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, err
+	}
+
+	record := &SubSystemRecord{}
+	err = json.Unmarshal(data, record)
+	if err != nil {
+		return nil, err
+	}
+
+	return record, nil
+}
+
+// ListRecordedSubSystems lists the subsystems known from their records,
+// without listing the containers, so rootful subsystems can be listed
+// without going through pkexec. Their status is not known.
+func ListRecordedSubSystems(isRootFull bool) ([]*SubSystem, error) {
+	entries, err := os.ReadDir(recordsDir(isRootFull))
+	if err != nil {
+		if os.IsNotExist(err) {
+			return []*SubSystem{}, nil
+		}
+		return nil, err
+	}
+
+	subSystems := make([]*SubSystem, 0)
+	for _, entry := range entries {
+		internalName, ok := strings.CutSuffix(entry.Name(), ".json")
+		if !ok {
+			continue
+		}
+
+		record, err := loadRecord(internalName, isRootFull)
+		if err != nil {
+			log.Printf("Error loading subsystem record %s: %s", internalName, err)
+			continue
+		}
+		if record == nil || record.IsRootfull != isRootFull {
+			continue
+		}
+
+		subSystem := newSubSystemFromRecord(internalName, record)
+		subSystem.Status = ""
+		subSystems = append(subSystems, subSystem)
+	}
+
+	return subSystems, nil
+}
+
+// saveRecord writes the record of the subsystem.
+func (s *SubSystem) saveRecord() error {
+	if IsDryRun() {
+		return nil
+	}
+
+	record := &SubSystemRecord{
+		Name:                 s.Name,
+		Stack:                s.Stack,
+		HasInit:              s.HasInit,
+		IsManaged:            s.IsManaged,
+		IsRootfull:           s.IsRootfull,
+		IsUnshared:           s.IsUnshared,
+		HasNvidiaIntegration: s.HasNvidiaIntegration,
+		Home:                 s.Home,
+		Hostname:             s.Hostname,
+		Mounts:               s.Mounts,
+		Profile:              s.Profile,
+		Image:                s.Image,
+		Env:                  s.Env,
+		EngineFlags:          s.EngineFlags,
+		CreatedAt:            s.CreatedAt,
+	}
+
+	data, err := json.MarshalIndent(record, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	path := recordPath(s.InternalName, s.IsRootfull)
+	err = os.MkdirAll(filepath.Dir(path), 0755)
+	if err != nil {
+		return err
+	}
+
+	return os.WriteFile(path, data, 0644)
+}
+
+// ForgetRecord deletes the record of a subsystem.
+func (s *SubSystem) ForgetRecord() error {
+	if IsDryRun() {
+		return nil
+	}
+
+	err := os.Remove(recordPath(s.InternalName, s.IsRootfull))
+	if err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	return nil
+}
+
+// newSubSystemFromContainer builds a subsystem from its container, merged
+// with its record when there is one. Containers without a record are read
+// from their labels and their stack is loaded by name, a placeholder is
+// used if it was deleted.
+func newSubSystemFromContainer(container *DBoxContainer, isRootFull bool) (*SubSystem, error) {
+	internalName := genInternalName(container.Labels["name"])
+	record, err := loadRecord(internalName, isRootFull)
+	if err != nil {
+		return nil, err
+	}
+
+	if record == nil {
+		stack, err := LoadStack(container.Labels["stack"])
+		if err != nil {
+			stack = &Stack{Name: container.Labels["stack"]}
+		}
+
+		record = &SubSystemRecord{
+			Name:                 container.Labels["name"],
+			Stack:                stack,
+			HasInit:              container.Labels["hasInit"] == "true",
+			IsManaged:            container.Labels["managed"] == "true",
+			IsRootfull:           isRootFull,
+			IsUnshared:           container.Labels["unshared"] == "true",
+			HasNvidiaIntegration: container.Labels["nvidia"] == "true",
+		}
+	}
+
+	subSystem := newSubSystemFromRecord(internalName, record)
+	subSystem.Status = container.Status
+	subSystem.IsRootfull = isRootFull
+
+	return subSystem, nil
+}
+
+// newSubSystemFromRecord builds a subsystem from its record alone. Its
+// stack is the snapshot taken at creation, see StackDrift for the changes
+// made to the stack since.
+func newSubSystemFromRecord(internalName string, record *SubSystemRecord) *SubSystem {
+	return &SubSystem{
+		InternalName:         internalName,
+		Name:                 record.Name,
+		Stack:                record.Stack,
+		Status:               SubSystemStatusMissing,
+		HasInit:              record.HasInit,
+		IsManaged:            record.IsManaged,
+		IsRootfull:           record.IsRootfull,
+		IsUnshared:           record.IsUnshared,
+		HasNvidiaIntegration: record.HasNvidiaIntegration,
+		Home:                 record.Home,
+		Hostname:             record.Hostname,
+		Mounts:               record.Mounts,
+		Profile:              record.Profile,
+		Image:                record.Image,
+		Env:                  record.Env,
+		EngineFlags:          record.EngineFlags,
+		CreatedAt:            record.CreatedAt,
+	}
+}
@@ -0,0 +1,91 @@
+package core
+
+import (
+	"fmt"
+	"strings"
+)
+
+// StackRepository is an extra repository a stack needs before its packages
+// can be installed.
+type StackRepository struct {
+	Name   string
+	Source string // Repository definition passed to the package manager CmdAddRepo
+	Key    string // Optional signing key passed to the package manager CmdImportKey
+}
+
+// GenRepoCmd builds the command adding a repository or importing its key.
+// The {name}, {source} and {key} placeholders are replaced with the
+// shell-quoted repository values and the command is run through a shell, so
+// it can use pipes and redirections. Without placeholders, the value is
+// appended as the last argument, like GenCmd does.
+func (pm *PkgManager) GenRepoCmd(cmd string, repo StackRepository, value string) []string {
+	if !strings.Contains(cmd, "{name}") && !strings.Contains(cmd, "{source}") && !strings.Contains(cmd, "{key}") {
+		return pm.GenCmd(cmd, value)
+	}
+
+	replacer := strings.NewReplacer(
+		"{name}", shellQuote(repo.Name),
+		"{source}", shellQuote(repo.Source),
+		"{key}", shellQuote(repo.Key),
+	)
+
+	finalArgs := []string{}
+	if pm.NeedSudo {
+		finalArgs = append(finalArgs, "sudo")
+	}
+
+	return append(finalArgs, "sh", "-c", replacer.Replace(cmd))
+}
+
+// setupPackages imports the signing keys and adds the repositories of the
+// stack, refreshes the package index, then installs the stack packages,
+// which may come from those repositories.
+func (s *SubSystem) setupPackages(backend Backend) error {
+	pkgManager, err := s.Stack.GetPkgManager()
+	if err != nil {
+		return err
+	}
+
+	if len(s.Stack.Repositories) > 0 && pkgManager.CmdAddRepo == "" {
+		return fmt.Errorf("package manager %s can't add repositories", pkgManager.Name)
+	}
+
+	exec := func(args []string) error {
+		_, err := backend.ContainerExec(s.InternalName, false, false, s.IsRootfull, false, args...)
+		return err
+	}
+
+	for _, repo := range s.Stack.Repositories {
+		if repo.Key != "" {
+			if pkgManager.CmdImportKey == "" {
+				return fmt.Errorf("package manager %s can't import signing keys", pkgManager.Name)
+			}
+
+			err := exec(pkgManager.GenRepoCmd(pkgManager.CmdImportKey, repo, repo.Key))
+			if err != nil {
+				return fmt.Errorf("importing key of repository %s: %w", repo.Name, err)
+			}
+		}
+
+		err := exec(pkgManager.GenRepoCmd(pkgManager.CmdAddRepo, repo, repo.Source))
+		if err != nil {
+			return fmt.Errorf("adding repository %s: %w", repo.Name, err)
+		}
+	}
+
+	if pkgManager.CmdUpdate != "" {
+		err := exec(pkgManager.GenCmd(pkgManager.CmdUpdate))
+		if err != nil {
+			return fmt.Errorf("refreshing repositories: %w", err)
+		}
+	}
+
+	if len(s.Stack.Packages) > 0 {
+		err := exec(pkgManager.GenCmd(pkgManager.CmdInstall, s.Stack.Packages...))
+		if err != nil {
+			return fmt.Errorf("installing packages: %w", err)
+		}
+	}
+
+	return nil
+}
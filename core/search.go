@@ -0,0 +1,139 @@
+package core
+
+import (
+	"fmt"
+	"sort"
+	"sync"
+)
+
+// SearchOffer is a subsystem able to install a package found by
+// SearchPackages.
+type SearchOffer struct {
+	SubSystem  string `json:"subsystem"`
+	Stack      string `json:"stack"`
+	PkgManager string `json:"pkgManager"`
+	Version    string `json:"version,omitempty"`
+	Repo       string `json:"repo,omitempty"`
+}
+
+// SearchMatch is a package found by SearchPackages, with every subsystem
+// offering it.
+type SearchMatch struct {
+	Name        string         `json:"name"`
+	Description string         `json:"description,omitempty"`
+	Offers      []*SearchOffer `json:"offers"`
+}
+
+// SearchPackages runs the search command of every subsystem, at most
+// DefaultMaintenanceJobs at the same time, and merges the results by
+// package name. Stopped subsystems are started for the search and stopped
+// again afterwards. Subsystems whose search fails are reported in the
+// returned map, keyed by subsystem name, and don't prevent the others from
+// being searched.
+func SearchPackages(query string) ([]*SearchMatch, map[string]error, error) {
+	subSystems, err := ListSubSystems(false, false)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	results := make([][]Package, len(subSystems))
+	errs := make([]error, len(subSystems))
+	slots := make(chan struct{}, DefaultMaintenanceJobs)
+
+	var wg sync.WaitGroup
+	for i, subSystem := range subSystems {
+		wg.Add(1)
+		go func(i int, subSystem *SubSystem) {
+			defer wg.Done()
+
+			slots <- struct{}{}
+			results[i], errs[i] = subSystem.searchPackages(query)
+			<-slots
+		}(i, subSystem)
+	}
+	wg.Wait()
+
+	failed := map[string]error{}
+	matches := map[string]*SearchMatch{}
+	for i, subSystem := range subSystems {
+		if errs[i] != nil {
+			failed[subSystem.Name] = errs[i]
+			continue
+		}
+
+		seen := map[string]bool{}
+		for _, pkg := range results[i] {
+			// Several architectures of the same package are offered once
+			if seen[pkg.Name] {
+				continue
+			}
+			seen[pkg.Name] = true
+
+			match, ok := matches[pkg.Name]
+			if !ok {
+				match = &SearchMatch{Name: pkg.Name}
+				matches[pkg.Name] = match
+			}
+			if match.Description == "" {
+				match.Description = pkg.Description
+			}
+
+			match.Offers = append(match.Offers, &SearchOffer{
+				SubSystem:  subSystem.Name,
+				Stack:      subSystem.Stack.Name,
+				PkgManager: subSystem.Stack.PkgManager,
+				Version:    pkg.Version,
+				Repo:       pkg.Repo,
+			})
+		}
+	}
+
+	sorted := make([]*SearchMatch, 0, len(matches))
+	for _, match := range matches {
+		sorted = append(sorted, match)
+	}
+	sort.Slice(sorted, func(i, j int) bool {
+		return sorted[i].Name < sorted[j].Name
+	})
+
+	return sorted, failed, nil
+}
+
+// searchPackages runs the search command of the subsystem package manager
+// and parses its output. A stopped subsystem is stopped again once searched.
+func (s *SubSystem) searchPackages(query string) (packages []Package, err error) {
+	pkgManager, err := s.Stack.GetPkgManager()
+	if err != nil {
+		return nil, err
+	}
+
+	if pkgManager.CmdSearch == "" {
+		return nil, fmt.Errorf("package manager %s has no search command", pkgManager.Name)
+	}
+
+	if !s.IsRunning() {
+		backend, err := NewBackend()
+		if err != nil {
+			return nil, err
+		}
+
+		err = backend.ContainerStart(s.InternalName, s.IsRootfull)
+		if err != nil {
+			return nil, fmt.Errorf("starting container: %w", err)
+		}
+
+		defer func() {
+			stopErr := backend.ContainerStop(s.InternalName, s.IsRootfull)
+			if err == nil && stopErr != nil {
+				packages, err = nil, fmt.Errorf("stopping container: %w", stopErr)
+			}
+		}()
+	}
+
+	out, err := s.Query(pkgManager.GenCmd(pkgManager.CmdSearch, query)...)
+	if err != nil {
+		return nil, err
+	}
+
+	return pkgManager.ParseOutput("search", out)
+}
@@ -0,0 +1,134 @@
+package core
+
+import (
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+)
+
+func writeSearchStack(t *testing.T) {
+	t.Helper()
+
+	writeTestPkgManager(t)
+	writeTestFile(t, filepath.Join(abg.Cnf.UserStacksPath, "ubuntu.yaml"), `
+name: ubuntu
+base: docker.io/library/ubuntu:22.04
+pkgmanager: apt
+`)
+}
+
+func TestSearchPackagesBoundsConcurrency(t *testing.T) {
+	fake := setupTestAbg(t)
+	writeSearchStack(t)
+	addTestContainers(fake, "one", "two", "three", "four", "five", "six")
+
+	fake.Outputs["sudo apt search ripgrep"] = "ripgrep/jammy 13.0.0-2 amd64\n  Recursively searches directories for a regex pattern\n"
+	probe := probeConcurrency(fake, func(call FakeCall) bool { return call.Method == "query" })
+
+	matches, failed, err := SearchPackages("ripgrep")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(failed) != 0 {
+		t.Errorf("unexpected failures: %v", failed)
+	}
+	if len(matches) != 1 || len(matches[0].Offers) != 6 {
+		t.Fatalf("expected ripgrep offered by the 6 subsystems, got %+v", matches)
+	}
+	if matches[0].Description != "Recursively searches directories for a regex pattern" {
+		t.Errorf("unexpected description %q", matches[0].Description)
+	}
+	if probe.max() > DefaultMaintenanceJobs {
+		t.Errorf("%d subsystems searched at the same time, want at most %d", probe.max(), DefaultMaintenanceJobs)
+	}
+}
+
+func TestSearchPackagesStopsStoppedSubsystems(t *testing.T) {
+	fake := setupTestAbg(t)
+	writeSearchStack(t)
+	addTestContainers(fake, "running")
+	fake.Containers[false] = append(fake.Containers[false], DBoxContainer{
+		ID:     "stopped",
+		Name:   genInternalName("stopped"),
+		Status: "Exited (0) 2 hours ago",
+		Labels: map[string]string{"manager": "abg", "name": "stopped", "stack": "ubuntu"},
+	})
+
+	_, failed, err := SearchPackages("ripgrep")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(failed) != 0 {
+		t.Errorf("unexpected failures: %v", failed)
+	}
+
+	for _, method := range []string{"start", "stop"} {
+		calls := fake.CallsTo(method)
+		if len(calls) != 1 || calls[0].Name != "abg-stopped" {
+			t.Errorf("expected only the stopped subsystem to %s, got %+v", method, calls)
+		}
+	}
+}
+
+func TestSearchPackagesWarnsOnStderr(t *testing.T) {
+	fake := setupTestAbg(t)
+	writeTestFile(t, filepath.Join(abg.Cnf.UserPkgManagersPath, "apt.yaml"), `
+name: apt
+needsudo: true
+cmdsearch: search
+`)
+	writeTestFile(t, filepath.Join(abg.Cnf.UserStacksPath, "ubuntu.yaml"), `
+name: ubuntu
+base: docker.io/library/ubuntu:22.04
+pkgmanager: apt
+`)
+	addTestContainers(fake, "one", "two")
+	fake.Outputs["sudo apt search ripgrep"] = "ripgrep/jammy 13.0.0-2 amd64\n"
+
+	dir := t.TempDir()
+	stdout, err := os.Create(filepath.Join(dir, "stdout"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	stderr, err := os.Create(filepath.Join(dir, "stderr"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	previousStdout, previousStderr := os.Stdout, os.Stderr
+	os.Stdout, os.Stderr = stdout, stderr
+	t.Cleanup(func() { os.Stdout, os.Stderr = previousStdout, previousStderr })
+
+	matches, _, err := SearchPackages("ripgrep")
+	os.Stdout, os.Stderr = previousStdout, previousStderr
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(matches) != 1 {
+		t.Errorf("expected one match, got %+v", matches)
+	}
+
+	out, err := os.ReadFile(stdout.Name())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(out) != 0 {
+		t.Errorf("nothing should be printed on stdout, got %q", out)
+	}
+
+	warnings, err := os.ReadFile(stderr.Name())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if strings.Count(string(warnings), "DEPRECATION WARNING") != 2 {
+		t.Errorf("expected a deprecation warning per subsystem on stderr, got %q", warnings)
+	}
+
+	for _, call := range fake.CallsTo("query") {
+		if !slices.Equal(call.Args, []string{"sudo", "apt", "search", "ripgrep"}) {
+			t.Errorf("unexpected search command %v", call.Args)
+		}
+	}
+}
@@ -2,20 +2,29 @@ package core
 
 import (
 	"errors"
+	"fmt"
 	"io"
 	"os"
 	"path/filepath"
+	"slices"
+	"strings"
 
 	"gopkg.in/yaml.v2"
 )
 
 // Stack represents a stack in AuruOS, a set of instructions to build a container.
 type Stack struct {
-	Name       string
-	Base       string
-	Packages   []string
-	PkgManager string
-	BuiltIn    bool // If true, the stack is built-in (stored in /usr/share/abg/stacks) and cannot be removed by the user
+	Name         string
+	Extends      string // Parent stack, its base, package manager and packages are inherited
+	Base         string
+	Packages     []string
+	PkgManager   string
+	Repositories []StackRepository // Added before the packages are installed
+	PostCreate   []string          // Commands run in order inside the container after its creation
+	PreRemove    []string          // Commands run in order inside the container before its removal
+	Mounts       []Mount           // Bind mounts, tmpfs and devices of every subsystem of the stack
+	Profile      string            // Security profile of the subsystems, see LoadSecurityProfile
+	BuiltIn      bool              // If true, the stack is built-in (stored in /usr/share/abg/stacks) and cannot be removed by the user
 }
 
 // NewStack creates a new Stack instance.
@@ -29,25 +38,54 @@ func NewStack(name, base string, packages []string, pkgManager string, builtIn b
 	}
 }
 
-// LoadStack loads a stack from the specified path.
+// LoadStack loads a stack by name, merged with the stacks it extends.
 func LoadStack(name string) (*Stack, error) {
+	stack, err := loadRawStack(name)
+	if err != nil {
+		return nil, err
+	}
+
+	return resolveStack(stack, []string{stack.Name})
+}
+
+// LoadStackFromPath loads a stack from the specified path, merged with the
+// stacks it extends.
+func LoadStackFromPath(path string) (*Stack, error) {
+	stack, err := loadStackFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	return resolveStack(stack, []string{stack.Name})
+}
+
+// LoadStackDefinition loads a stack by name as it is stored, without the
+// values inherited from the stacks it extends.
+func LoadStackDefinition(name string) (*Stack, error) {
+	return loadRawStack(name)
+}
+
+// loadRawStack loads a stack by name as it is stored, without resolving
+// inheritance. User stacks take precedence over the built-in ones.
+func loadRawStack(name string) (*Stack, error) {
 	usrStackFile := SelectYamlFile(abg.Cnf.UserStacksPath, name)
-	stack, err := LoadStackFromPath(usrStackFile)
+	stack, err := loadStackFile(usrStackFile)
 	if err != nil {
 		stackFile := SelectYamlFile(abg.Cnf.StacksPath, name)
-		stack, err = LoadStackFromPath(stackFile)
+		stack, err = loadStackFile(stackFile)
 	}
 	return stack, err
 }
 
-// LoadStackFromPath loads a stack from the specified path.
-func LoadStackFromPath(path string) (*Stack, error) {
+// loadStackFile reads a stack file without resolving inheritance.
+func loadStackFile(path string) (*Stack, error) {
 	stack := &Stack{}
 
 	f, err := os.Open(path)
 	if err != nil {
 		return nil, errors.New("stack not found")
 	}
+	defer f.Close()
 
 	data, err := io.ReadAll(f)
 	if err != nil {
@@ -59,16 +97,151 @@ func LoadStackFromPath(path string) (*Stack, error) {
 		return nil, err
 	}
 
-	if stack.Name == "" || stack.Base == "" || stack.PkgManager == "" {
+	if stack.Name == "" {
+		return nil, errors.New("invalid stack file")
+	}
+
+	if stack.Extends == "" && (stack.Base == "" || stack.PkgManager == "") {
 		return nil, errors.New("invalid stack file")
 	}
 
 	return stack, nil
 }
 
+// resolveStack merges a stack with its ancestors. The base, package manager
+// and security profile are inherited unless overridden, the packages,
+// repositories, mounts and hooks are added to the parent ones. chain holds
+// the names already visited to detect cycles.
+func resolveStack(stack *Stack, chain []string) (*Stack, error) {
+	if stack.Extends == "" {
+		return stack, nil
+	}
+
+	if slices.Contains(chain, stack.Extends) {
+		return nil, fmt.Errorf("stack inheritance cycle: %s -> %s", strings.Join(chain, " -> "), stack.Extends)
+	}
+
+	rawParent, err := loadRawStack(stack.Extends)
+	if err != nil {
+		return nil, fmt.Errorf("parent stack %s of %s: %w", stack.Extends, stack.Name, err)
+	}
+
+	parent, err := resolveStack(rawParent, append(chain, rawParent.Name))
+	if err != nil {
+		return nil, err
+	}
+
+	resolved := *stack
+	if resolved.Base == "" {
+		resolved.Base = parent.Base
+	}
+	if resolved.PkgManager == "" {
+		resolved.PkgManager = parent.PkgManager
+	}
+	if resolved.Profile == "" {
+		resolved.Profile = parent.Profile
+	}
+
+	resolved.Packages = slices.Clone(parent.Packages)
+	for _, pkg := range stack.Packages {
+		if !slices.Contains(resolved.Packages, pkg) {
+			resolved.Packages = append(resolved.Packages, pkg)
+		}
+	}
+
+	resolved.Repositories = slices.Clone(parent.Repositories)
+	for _, repo := range stack.Repositories {
+		if !slices.ContainsFunc(resolved.Repositories, func(r StackRepository) bool { return r.Name == repo.Name }) {
+			resolved.Repositories = append(resolved.Repositories, repo)
+		}
+	}
+
+	resolved.Mounts = slices.Clone(parent.Mounts)
+	for _, mount := range stack.Mounts {
+		if !slices.ContainsFunc(resolved.Mounts, func(m Mount) bool { return m.target() == mount.target() }) {
+			resolved.Mounts = append(resolved.Mounts, mount)
+		}
+	}
+
+	// Parent hooks run first, so children can rely on their setup
+	resolved.PostCreate = append(slices.Clone(parent.PostCreate), stack.PostCreate...)
+	resolved.PreRemove = append(slices.Clone(parent.PreRemove), stack.PreRemove...)
+
+	return &resolved, nil
+}
+
+// definition returns the stack as it has to be stored: values inherited
+// from the parent stack are stripped so they keep following it.
+func (stack *Stack) definition() (*Stack, error) {
+	if stack.Extends == "" {
+		return stack, nil
+	}
+
+	_, err := resolveStack(stack, []string{stack.Name})
+	if err != nil {
+		return nil, err
+	}
+
+	parent, err := LoadStack(stack.Extends)
+	if err != nil {
+		return nil, err
+	}
+
+	def := *stack
+	if def.Base == parent.Base {
+		def.Base = ""
+	}
+	if def.PkgManager == parent.PkgManager {
+		def.PkgManager = ""
+	}
+	if def.Profile == parent.Profile {
+		def.Profile = ""
+	}
+
+	def.Packages = make([]string, 0)
+	for _, pkg := range stack.Packages {
+		if !slices.Contains(parent.Packages, pkg) {
+			def.Packages = append(def.Packages, pkg)
+		}
+	}
+
+	def.Repositories = make([]StackRepository, 0)
+	for _, repo := range stack.Repositories {
+		if !slices.Contains(parent.Repositories, repo) {
+			def.Repositories = append(def.Repositories, repo)
+		}
+	}
+
+	def.Mounts = make([]Mount, 0)
+	for _, mount := range stack.Mounts {
+		if !slices.Contains(parent.Mounts, mount) {
+			def.Mounts = append(def.Mounts, mount)
+		}
+	}
+
+	def.PostCreate = trimHooksPrefix(stack.PostCreate, parent.PostCreate)
+	def.PreRemove = trimHooksPrefix(stack.PreRemove, parent.PreRemove)
+
+	return &def, nil
+}
+
+// trimHooksPrefix removes the inherited hooks from the beginning of a
+// resolved hooks list.
+func trimHooksPrefix(hooks, inherited []string) []string {
+	if len(hooks) >= len(inherited) && slices.Equal(hooks[:len(inherited)], inherited) {
+		return hooks[len(inherited):]
+	}
+	return hooks
+}
+
 // Save saves the stack to a YAML file.
 func (stack *Stack) Save() error {
-	data, err := yaml.Marshal(stack)
+	def, err := stack.definition()
+	if err != nil {
+		return err
+	}
+
+	data, err := yaml.Marshal(def)
 	if err != nil {
 		return err
 	}
@@ -94,6 +267,10 @@ func (stack *Stack) Remove() error {
 		return errors.New("cannot remove built-in stack")
 	}
 
+	if len(ListStackChildren(stack.Name)) > 0 {
+		return errors.New("cannot remove a stack extended by other stacks")
+	}
+
 	filePath := SelectYamlFile(abg.Cnf.UserStacksPath, stack.Name)
 	err := os.Remove(filePath)
 	return err
@@ -108,8 +285,13 @@ func (stack *Stack) Export(path string) error {
 		}
 	}
 
+	def, err := stack.definition()
+	if err != nil {
+		return err
+	}
+
 	filePath := SelectYamlFile(path, stack.Name)
-	data, err := yaml.Marshal(stack)
+	data, err := yaml.Marshal(def)
 	if err != nil {
 		return err
 	}
@@ -161,6 +343,20 @@ func ListStackForPkgManager(pkgManager string) []*Stack {
 	return stacks
 }
 
+// ListStackChildren returns the stacks directly extending the specified
+// stack.
+func ListStackChildren(name string) []*Stack {
+	children := make([]*Stack, 0)
+
+	for _, stack := range ListStacks() {
+		if stack.Extends == name {
+			children = append(children, stack)
+		}
+	}
+
+	return children
+}
+
 // listStacksFromPath returns a list of stacks from the specified path.
 // this func does not return an error, since AuruOS is meant to be portable and
 // the main directory can be missing, while the user directory is always created.
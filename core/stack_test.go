@@ -0,0 +1,104 @@
+package core
+
+import (
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+)
+
+func TestLoadStack(t *testing.T) {
+	setupTestAbg(t)
+
+	writeTestFile(t, filepath.Join(abg.Cnf.StacksPath, "ubuntu.yaml"), `
+name: ubuntu
+base: docker.io/library/ubuntu:22.04
+pkgmanager: apt
+packages: [curl]
+builtin: true
+`)
+
+	stack, err := LoadStack("ubuntu")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if stack.Base != "docker.io/library/ubuntu:22.04" || stack.PkgManager != "apt" || !stack.BuiltIn {
+		t.Errorf("unexpected stack: %+v", stack)
+	}
+	if !slices.Equal(stack.Packages, []string{"curl"}) {
+		t.Errorf("unexpected packages: %v", stack.Packages)
+	}
+
+	// User stacks take precedence over the built-in ones
+	writeTestFile(t, filepath.Join(abg.Cnf.UserStacksPath, "ubuntu.yml"), `
+name: ubuntu
+base: docker.io/library/ubuntu:24.04
+pkgmanager: apt
+`)
+
+	stack, err = LoadStack("ubuntu")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if stack.Base != "docker.io/library/ubuntu:24.04" {
+		t.Errorf("user stack not preferred, base is %s", stack.Base)
+	}
+
+	_, err = LoadStack("missing")
+	if err == nil {
+		t.Error("loading a missing stack should fail")
+	}
+}
+
+func TestLoadStackInvalid(t *testing.T) {
+	setupTestAbg(t)
+
+	writeTestFile(t, filepath.Join(abg.Cnf.UserStacksPath, "nobase.yaml"), `
+name: nobase
+pkgmanager: apt
+`)
+
+	_, err := LoadStack("nobase")
+	if err == nil {
+		t.Error("a stack without base should be invalid")
+	}
+}
+
+func TestLoadStackExtends(t *testing.T) {
+	setupTestAbg(t)
+
+	writeTestFile(t, filepath.Join(abg.Cnf.UserStacksPath, "base.yaml"), `
+name: base
+base: docker.io/library/debian:12
+pkgmanager: apt
+packages: [curl, git]
+`)
+	writeTestFile(t, filepath.Join(abg.Cnf.UserStacksPath, "dev.yaml"), `
+name: dev
+extends: base
+packages: [git, make]
+`)
+
+	stack, err := LoadStack("dev")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if stack.Base != "docker.io/library/debian:12" || stack.PkgManager != "apt" {
+		t.Errorf("base and package manager not inherited: %+v", stack)
+	}
+	if !slices.Equal(stack.Packages, []string{"curl", "git", "make"}) {
+		t.Errorf("unexpected packages: %v", stack.Packages)
+	}
+}
+
+func TestLoadStackExtendsCycle(t *testing.T) {
+	setupTestAbg(t)
+
+	writeTestFile(t, filepath.Join(abg.Cnf.UserStacksPath, "a.yaml"), "name: a\nextends: b\n")
+	writeTestFile(t, filepath.Join(abg.Cnf.UserStacksPath, "b.yaml"), "name: b\nextends: a\n")
+
+	_, err := LoadStack("a")
+	if err == nil || !strings.Contains(err.Error(), "cycle") {
+		t.Errorf("expected a cycle error, got %v", err)
+	}
+}
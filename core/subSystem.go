@@ -1,12 +1,13 @@
-
-package main
+package core
 
 import (
 	"fmt"
 	"log"
 	"os"
 	"path/filepath"
+	"slices"
 	"strings"
+	"time"
 
 	"github.com/google/uuid"
 )
@@ -21,29 +22,79 @@ type SubSystem struct {
 	IsRootfull           bool
 	IsUnshared           bool
 	HasNvidiaIntegration bool
+	Home                 string
+	Hostname             string
+	Mounts               []Mount // Added to the stack mounts
+	Profile              string  // Security profile, the stack one is used if empty
+	Image                string  // Image committed after the setup of a read-only root
+	Env                  []string
+	EngineFlags          []string
+	CreatedAt            time.Time
 	ExportedPrograms     map[string]map[string]string
+	Exports              []*ExportEntry
 }
 
-func findExported(internalName string, name string) map[string]map[string]string {
-	bins := findExportedBinaries(internalName)
-	progs := findExportedPrograms(internalName, name)
+// SubSystemStatusMissing is the status of a subsystem known from its record
+// whose container doesn't exist anymore. It can be reset or removed.
+const SubSystemStatusMissing = "missing"
 
-	for k, v := range progs {
-		bins[k] = v // Give priority to application if duplicate is found.
-	}
+// NewSubSystem returns a subsystem which doesn't exist yet, Create creates
+// its container.
+func NewSubSystem(name string, stack *Stack, home string, hasInit, isManaged, isRootfull, isUnshared, hasNvidiaIntegration bool, hostname string) (*SubSystem, error) {
+	return &SubSystem{
+		InternalName:         genInternalName(name),
+		Name:                 name,
+		Stack:                stack,
+		HasInit:              hasInit,
+		IsManaged:            isManaged,
+		IsRootfull:           isRootfull,
+		IsUnshared:           isUnshared,
+		HasNvidiaIntegration: hasNvidiaIntegration,
+		Home:                 home,
+		Hostname:             hostname,
+	}, nil
+}
 
-	return bins
+// genInternalName returns the container name of a subsystem.
+func genInternalName(name string) string {
+	return fmt.Sprintf("abg-%s", strings.ReplaceAll(strings.ToLower(name), " ", "-"))
 }
 
 func (s *SubSystem) Create() error {
-	dbox, err := NewDbox()
+	return s.create(nil)
+}
+
+// create creates the container and sets it up. The tracked changes, if
+// given, are restored as part of the setup, before a read-only root is
+// sealed.
+func (s *SubSystem) create(tracking *Tracking) error {
+	mounts := append(slices.Clone(s.Stack.Mounts), s.Mounts...)
+	extras := ContainerExtras{
+		Env:         s.Env,
+		EngineFlags: s.EngineFlags,
+	}
+	err := extras.addMounts(mounts)
+	if err != nil {
+		return err
+	}
+	err = extras.Validate()
+	if err != nil {
+		return err
+	}
+
+	profile, err := s.SecurityProfile()
+	if err != nil {
+		return err
+	}
+
+	backend, err := NewBackend()
 	if err != nil {
 		return err
 	}
 
 	labels := map[string]string{
-		"stack": strings.ReplaceAll(s.Stack.Name, " ", "\\ "),
-		"name":  strings.ReplaceAll(s.Name, " ", "\\ "),
+		"stack": s.Stack.Name,
+		"name":  s.Name,
 	}
 
 	if s.IsManaged {
@@ -59,327 +110,596 @@ func (s *SubSystem) Create() error {
 		labels["nvidia"] = "true"
 	}
 
-	return dbox.CreateContainer(
-		s.InternalName,
-		s.Stack.Base,
-		s.Stack.Packages,
-		s.Home,
-		labels,
-		s.HasInit,
-		s.IsRootfull,
-		s.IsUnshared,
-		s.HasNvidiaIntegration,
-		s.Hostname,
-    )
+	// Packages may come from the stack repositories, and not every backend
+	// installs them at creation, in both cases they are installed through
+	// the package manager once the container exists
+	packages := s.Stack.Packages
+	setupPackages := len(s.Stack.Repositories) > 0 || (len(packages) > 0 && !backend.InstallsPackages())
+	if setupPackages {
+		packages = nil
+	}
+	restoreTracking := tracking != nil && (len(tracking.Installed) > 0 || len(tracking.Removed) > 0)
+
+	home, withNvidia, err := profile.apply(s, mounts, &extras)
+	if err != nil {
+		return err
+	}
+
+	// Nothing can be written in a read-only root once the container exists,
+	// so it is set up writable first, then sealed
+	seal := extras.ReadOnlyRoot && (setupPackages || restoreTracking || len(s.Stack.PostCreate) > 0)
+	if seal {
+		extras.ReadOnlyRoot = false
+	}
+
+	createContainer := func(image string, extras ContainerExtras) error {
+		return backend.CreateContainer(
+			s.InternalName,
+			image,
+			packages,
+			home,
+			labels,
+			s.HasInit,
+			s.IsRootfull,
+			s.IsUnshared,
+			withNvidia,
+			s.Hostname,
+			extras,
+		)
+	}
+
+	err = createContainer(s.Stack.Base, extras)
+	if err != nil {
+		return err
+	}
+
+	// A subsystem whose setup failed is unusable, roll it back
+	if setupPackages {
+		err = s.setupPackages(backend)
+		if err != nil {
+			_ = backend.ContainerDelete(s.InternalName, s.IsRootfull)
+			return fmt.Errorf("packages setup failed, subsystem removed: %w", err)
+		}
+	}
+
+	err = s.runHooks(backend, s.Stack.PostCreate)
+	if err != nil {
+		_ = backend.ContainerDelete(s.InternalName, s.IsRootfull)
+		return fmt.Errorf("post-create failed, subsystem removed: %w", err)
+	}
+
+	if restoreTracking {
+		err = s.restoreTracking(tracking)
+		if err != nil {
+			// A writable root would not match the security profile
+			if seal {
+				_ = backend.ContainerDelete(s.InternalName, s.IsRootfull)
+			}
+			return err
+		}
+	}
+
+	s.Image = ""
+	if seal {
+		image := sealedImageName(s.InternalName)
+		err = s.seal(backend, image, func() error {
+			extras.ReadOnlyRoot = true
+			return createContainer(image, extras)
+		})
+		if err != nil {
+			return fmt.Errorf("read-only root setup failed, subsystem removed: %w", err)
+		}
+		s.Image = image
+	}
+
+	s.CreatedAt = time.Now()
+	return s.saveRecord()
+}
+
+// sealedImageName returns the image a subsystem with a read-only root is
+// recreated from once set up.
+func sealedImageName(internalName string) string {
+	return "localhost/" + internalName + ":sealed"
+}
+
+// seal commits the set up container to the given image and replaces it by
+// the container recreate creates from it. The subsystem is removed if any
+// step fails.
+func (s *SubSystem) seal(backend Backend, image string, recreate func() error) error {
+	err := backend.ContainerCommit(s.InternalName, image, s.IsRootfull)
+	if err != nil {
+		_ = backend.ContainerDelete(s.InternalName, s.IsRootfull)
+		return err
+	}
+
+	err = backend.ContainerDelete(s.InternalName, s.IsRootfull)
+	if err == nil {
+		err = recreate()
+	}
+	if err != nil {
+		_ = backend.ContainerDelete(s.InternalName, s.IsRootfull)
+		_ = backend.ImageDelete(image, s.IsRootfull)
+		return err
+	}
+
+	return nil
 }
 
+// deleteImage deletes the image a sealed subsystem was created from.
+// Failures are only logged, the image is recreated or left unused.
+func (s *SubSystem) deleteImage(backend Backend) {
+	if s.Image == "" {
+		return
+	}
+
+	err := backend.ImageDelete(s.Image, s.IsRootfull)
+	if err != nil {
+		log.Printf("Removing image %s of %s failed: %s", s.Image, s.Name, err)
+	}
+}
+
+// runHooks runs the given stack hooks inside the container, in order,
+// stopping at the first failure.
+func (s *SubSystem) runHooks(backend Backend, hooks []string) error {
+	for _, hook := range hooks {
+		_, err := backend.ContainerExec(s.InternalName, false, false, s.IsRootfull, false, "sh", "-c", hook)
+		if err != nil {
+			return fmt.Errorf("command %q: %w", hook, err)
+		}
+	}
+
+	return nil
+}
+
+// runPreRemove runs the pre-remove hooks of the stack. Failures are only
+// logged, a broken subsystem must still be removable.
+func (s *SubSystem) runPreRemove(backend Backend) {
+	err := s.runHooks(backend, s.Stack.PreRemove)
+	if err != nil {
+		log.Printf("Pre-remove of %s failed: %s", s.Name, err)
+	}
+}
+
+// LoadSubSystem loads a subsystem from its container and record. A
+// subsystem whose container was deleted is still loaded from its record,
+// with the SubSystemStatusMissing status.
 func LoadSubSystem(name string, isRootFull bool) (*SubSystem, error) {
-	dbox, err := NewDbox()
+	backend, err := NewBackend()
 	if err != nil {
-        return nil, err
-    }
+		return nil, err
+	}
 
 	internalName := genInternalName(name)
-	container, err := dbox.GetContainer(internalName, isRootFull)
+	container, err := backend.GetContainer(internalName, isRootFull)
 	if err != nil {
-        return nil, err
-    }
+		record, recordErr := loadRecord(internalName, isRootFull)
+		if recordErr != nil || record == nil || record.IsRootfull != isRootFull {
+			return nil, err
+		}
 
-	stack, err := LoadStack(container.Labels["stack"])
-	if err != nil {
-        return nil, err
-    }
+		return newSubSystemFromRecord(internalName, record), nil
+	}
 
-	return &SubSystem{
-        InternalName: internalName,
-        Name:         container.Labels["name"],
-        Stack:        stack,
-        Status:       container.Status,
-        HasInit:      container.Labels["hasInit"] == "true",
-        IsManaged:    container.Labels["managed"] == "true",
-        IsRootfull:   isRootFull,
-        IsUnshared:   container.Labels["unshared"] == "true",
-    }, nil
+	return newSubSystemFromContainer(container, isRootFull)
 }
 
+// ListSubSystems lists the rootless subsystems, and the rootful ones too
+// if includeRootFull is set. Listing rootful containers goes through
+// pkexec, see ListRecordedSubSystems to avoid it.
 func ListSubSystems(includeManaged bool, includeRootFull bool) ([]*SubSystem, error) {
-	dbox, err := NewDbox()
+	backend, err := NewBackend()
+	if err != nil {
+		return nil, err
+	}
+
+	exports, err := loadExportRegistry()
 	if err != nil {
-        return nil, err
-    }
+		return nil, err
+	}
 
-    containers, err := dbox.ListContainers(includeRootFull)
-    if err != nil {
-        return nil, err
-    }
+	rootFullModes := []bool{false}
+	if includeRootFull {
+		rootFullModes = append(rootFullModes, true)
+	}
 
 	subsystems := make([]*SubSystem, 0)
-	for _, container := range containers {
-	    if _, ok := container.Labels["name"]; !ok {
-	        continue // Skip containers without a name label.
-	    }
-
-	    if !includeManaged && container.Labels["managed"] == "true" {
-	        continue // Skip managed containers if not included.
-	    }
-
-	    stack, err := LoadStack(container.Labels["stack"])
-	    if err != nil {
-	        log.Printf("Error loading stack %s: %s", container.Labels["stack"], err)
-	        continue
-	    }
-
-	    internalName := genInternalName(container.Labels["name"])
-	    subsystem := &SubSystem{
-	        InternalName:     internalName,
-	        Name:             container.Labels["name"],
-	        Stack:            stack,
-	        Status:           container.Status,
-	        ExportedPrograms: findExported(internalName, container.Labels["name"]),
-	    }
-
-	    subsystems = append(subsystems, subsystem)
-    }
+	for _, rootFull := range rootFullModes {
+		containers, err := backend.ListContainers(rootFull)
+		if err != nil {
+			return nil, err
+		}
+
+		for _, container := range containers {
+			if _, ok := container.Labels["name"]; !ok {
+				continue // Skip containers without a name label.
+			}
+
+			if container.Labels["android"] == "true" {
+				continue // Android subsystems are listed by ListAndroidSubSystems.
+			}
+
+			subsystem, err := newSubSystemFromContainer(&container, rootFull)
+			if err != nil {
+				log.Printf("Error loading subsystem %s: %s", container.Labels["name"], err)
+				continue
+			}
+
+			if !includeManaged && subsystem.IsManaged {
+				continue // Skip managed containers if not included.
+			}
+
+			subsystem.Exports = exports[storageName(subsystem.InternalName, subsystem.IsRootfull)]
+			subsystem.ExportedPrograms = exportedPrograms(subsystem.Exports)
+			subsystems = append(subsystems, subsystem)
+		}
+	}
 
 	return subsystems, nil
 }
 
 // ListSubsystemForStack returns a list of subsystems for the specified stack.
 func ListSubsystemForStack(stackName string) ([]*SubSystem, error) {
-	dbox, err := NewDbox()
+	backend, err := NewBackend()
 	if err != nil {
-        return nil, err
-    }
-
-	rootlessContainers, err := dbox.ListContainers(false)
-	if err != nil {
-        return nil, err
-    }
+		return nil, err
+	}
 
-	rootfullContainers, err := dbox.ListContainers(true)
+	exports, err := loadExportRegistry()
 	if err != nil {
-        return nil, err
-    }
-
-	var containers []Container // Assuming Container is a defined type.
-	for _, c := range rootlessContainers {
-	    containers = append(containers, c)
-    }
-	for _, c := range rootfullContainers {
-	    containers = append(containers, c)
-    }
+		return nil, err
+	}
 
 	subsystems := make([]*SubSystem, 0)
-	for _, container := range containers {
-	    if _, ok := container.Labels["name"]; !ok {
-	        continue // Skip containers without a name label.
-	    }
-
-	    stack, err := LoadStack(stackName)
-	    if err != nil {
-	        log.Printf("Error loading stack %s: %s", stackName, err)
-	        continue
-	    }
-
-	    internalName := genInternalName(container.Labels["name"])
-	    subsystem := &SubSystem{
-	        InternalName:     internalName,
-	        Name:             container.Labels["name"],
-	        Stack:            stack,
-	        Status:           container.Status,
-	        ExportedPrograms: findExported(internalName, container.Labels["name"]),
-	    }
-
-	    if subsystem.Stack.Name == stack.Name { // Check for matching stack names.
-	        subsystems = append(subsystems, subsystem)
-	    }
-    }
+	for _, rootFull := range []bool{false, true} {
+		containers, err := backend.ListContainers(rootFull)
+		if err != nil {
+			return nil, err
+		}
+
+		for _, container := range containers {
+			if _, ok := container.Labels["name"]; !ok {
+				continue // Skip containers without a name label.
+			}
+
+			if container.Labels["android"] == "true" {
+				continue
+			}
+
+			subsystem, err := newSubSystemFromContainer(&container, rootFull)
+			if err != nil {
+				log.Printf("Error loading subsystem %s: %s", container.Labels["name"], err)
+				continue
+			}
+
+			if subsystem.Stack.Name == stackName {
+				subsystem.Exports = exports[storageName(subsystem.InternalName, subsystem.IsRootfull)]
+				subsystem.ExportedPrograms = exportedPrograms(subsystem.Exports)
+				subsystems = append(subsystems, subsystem)
+			}
+		}
+	}
 
 	return subsystems, nil
 }
 
 // Exec executes a command in the subsystem.
 func (s *SubSystem) Exec(captureOutput bool, detachedMode bool, args ...string) (string, error) {
-	dbox, err := NewDbox()
+	backend, err := NewBackend()
 	if err != nil {
-        return "", err
-    }
+		return "", err
+	}
 
-	outStrg ,err:= dbox.ContainerExec(s.InternalName,captureOutput,false,s.IsRootfull ,detachedMode,args...)
+	out, err := backend.ContainerExec(s.InternalName, captureOutput, false, s.IsRootfull, detachedMode, args...)
+	if !captureOutput {
+		out = ""
+	}
 
-	if captureOutput{
-	  return outStrg,nil
-	  }
+	return out, err
+}
 
-	return "",nil
+// Query runs a read-only command in the subsystem and returns its output.
+// Unlike Exec, it also runs in dry-run mode.
+func (s *SubSystem) Query(args ...string) (string, error) {
+	backend, err := NewBackend()
+	if err != nil {
+		return "", err
+	}
+
+	return backend.ContainerQuery(s.InternalName, s.IsRootfull, args...)
 }
 
 // Enter enters the subsystem's environment.
 func (s *SubSystem) Enter() error {
-	dbox ,err:= NewDbox()
-	if(err!=nil){
-	  return 	err
-	  }
-	  return dbox.ContainerEnter(s.InternalName,s.IsRootfull)
+	backend, err := NewBackend()
+	if err != nil {
+		return err
+	}
+	return backend.ContainerEnter(s.InternalName, s.IsRootfull)
 }
 
 // Start starts the subsystem.
 func (s *SubSystem) Start() error {
-	dbox ,err:= NewDbox()
-	if(err!=nil){
-	  return 	err
-	  }
-	  return dbox.ContainerStart(s.InternalName,s.IsRootfull)
+	backend, err := NewBackend()
+	if err != nil {
+		return err
+	}
+	return backend.ContainerStart(s.InternalName, s.IsRootfull)
 }
 
 // Stop stops the subsystem.
 func (s *SubSystem) Stop() error {
-	dbox ,err:= NewDbox()
-	if(err!=nil){
-	  return 	err
-	  }
-	  return dbox.ContainerStop(s.InternalName,s.IsRootfull)
+	backend, err := NewBackend()
+	if err != nil {
+		return err
+	}
+	return backend.ContainerStop(s.InternalName, s.IsRootfull)
 }
 
-// Remove deletes the subsystem.
-func (s *SubSystem) Remove() error {
-	dbox ,err:= NewDbox()
-	if(err!=nil){
-	  return 	err
-	  }
+// Remove deletes the subsystem, its exported files on the host, what was
+// tracked for it and its record. It returns the host files that were
+// removed.
+func (s *SubSystem) Remove() ([]string, error) {
+	backend, err := NewBackend()
+	if err != nil {
+		return nil, err
+	}
+
+	if s.Status != SubSystemStatusMissing {
+		s.runPreRemove(backend)
+
+		err = backend.ContainerDelete(s.InternalName, s.IsRootfull)
+		if err != nil {
+			return nil, err
+		}
+	}
+	s.deleteImage(backend)
 
-	return dbox.ContainerDelete(s.InternalName,s.IsRootfull)
+	removed, err := s.RemoveHostArtefacts()
+	if err != nil {
+		return removed, err
+	}
+
+	err = s.ForgetTracking()
+	if err != nil {
+		return removed, err
+	}
+
+	return removed, s.ForgetRecord()
 }
 
-// Reset removes and recreates the subsystem.
-func (s *SubSystem) Reset() error {
-	err:= s.Remove()
-	if(err!=nil){
-	   return 	err
-	   }
+// Reset removes and recreates the subsystem. Unless clean is set, packages
+// installed by the user and exported apps and binaries are restored.
+func (s *SubSystem) Reset(clean bool) error {
+	if clean {
+		_, err := s.Remove()
+		if err != nil {
+			return err
+		}
+
+		return s.Create()
+	}
+
+	tracking, err := s.LoadTracking()
+	if err != nil {
+		return err
+	}
+
+	exports, err := s.LoadExports()
+	if err != nil {
+		return err
+	}
+
+	backend, err := NewBackend()
+	if err != nil {
+		return err
+	}
+
+	if s.Status != SubSystemStatusMissing {
+		s.runPreRemove(backend)
+
+		err = backend.ContainerDelete(s.InternalName, s.IsRootfull)
+		if err != nil {
+			return err
+		}
+	}
+	s.deleteImage(backend)
 
-	return s.Create()
+	// The exports and tracking are only touched once the container is back,
+	// so a failed reset can be retried without losing them
+	err = s.create(tracking)
+	if err != nil {
+		return err
+	}
+
+	// Remove the old exports before restoring them, so they don't collide
+	_, err = s.RemoveExportedFiles()
+	if err != nil {
+		return err
+	}
+
+	return s.restoreExports(exports)
 }
 
 // ExportDesktopEntry exports a desktop entry for an application.
 func (s *SubSystem) ExportDesktopEntry(appName string) error {
-	dbox ,err:= NewDbox()
-	if(err!=nil){
-	   return 	err
-	   }
+	backend, err := NewBackend()
+	if err != nil {
+		return err
+	}
 
-	return dbox.ContainerExportDesktopEntry(s.InternalName ,appName ,fmt.Sprintf("on %s", s.Name), s.IsRootfull )
+	// The desktop files are named after the container ones, which may not
+	// contain the application name, so what the export wrote is recorded
+	appsDir, err := hostApplicationsDir()
+	if err != nil {
+		return err
+	}
+	before := snapshotDir(appsDir)
+
+	err = backend.ContainerExportDesktopEntry(s.InternalName, appName, fmt.Sprintf("on %s", s.Name), s.IsRootfull)
+	if err != nil {
+		return err
+	}
+
+	return registerExport(storageName(s.InternalName, s.IsRootfull), &ExportEntry{
+		Type:       ExportTypeApp,
+		Name:       appName,
+		HostFiles:  changedFiles(appsDir, before),
+		ExportedAt: time.Now(),
+	})
 }
 
 // ExportDesktopEntries exports multiple desktop entries for applications.
-func (s *SubSystem) ExportDesktopEntries(args ...string) (int,error){
-	exportedN:=0
+func (s *SubSystem) ExportDesktopEntries(args ...string) (int, error) {
+	exportedN := 0
 
-	for _, appName:=range args{
-	   if(err:= s.ExportDesktopEntry(appName);err!=nil){
-	      return exportedN ,err
-	      }
+	for _, appName := range args {
+		if err := s.ExportDesktopEntry(appName); err != nil {
+			return exportedN, err
+		}
 
-	   exportedN++
-	   }
+		exportedN++
+	}
 
-	return exportedN,nil
+	return exportedN, nil
 }
 
 // UnexportDesktopEntries unexports multiple desktop entries for applications.
-func (s *SubSystem) UnexportDesktopEntries(args ...string)(int,error){
-	exportedN:=0
+func (s *SubSystem) UnexportDesktopEntries(args ...string) (int, error) {
+	exportedN := 0
 
-	for _, appName:=range args{
-	   if(err:= s.UnexportDesktopEntry(appName);err!=nil){
-	      return exportedN ,err
-	      }
+	for _, appName := range args {
+		if err := s.UnexportDesktopEntry(appName); err != nil {
+			return exportedN, err
+		}
 
-	   exportedN++
-	   }
+		exportedN++
+	}
 
-	return exportedN,nil
+	return exportedN, nil
 }
 
 // ExportBin exports a binary to a specified path.
-func (s *SubSystem) ExportBin(binary string ,exportPath string)(error){
-	if !strings.HasPrefix(binary,"/"){
-	   binaryPath ,err:= s.Exec(true,false,"which",binary )
-	   if(err!=nil){
-	      return 	err
-	      }
-
-	   binary=strings.TrimSpace(binaryPath )
-	   }
+func (s *SubSystem) ExportBin(binary string, exportPath string) error {
+	if !strings.HasPrefix(binary, "/") {
+		binaryPath, err := s.Query("which", binary)
+		if err != nil {
+			return err
+		}
+
+		binary = strings.TrimSpace(binaryPath)
+	}
 
-	binaryName:=filepath.Base(binary )
+	binaryName := filepath.Base(binary)
 
-	dbox ,err:= NewDbox()
-	if(err!=nil){
-	   return 	err
-	   }
+	backend, err := NewBackend()
+	if err != nil {
+		return err
+	}
 
 	var homeDir string
 	var homeErr error
 
-	if homeDir ,homeErr= os.UserHomeDir();homeErr!=nil{
-	     return homeErr
-	     }
-
-	if exportPath==""{
-	     exportPath=filepath.Join(homeDir,".local","bin")
-	     }
-
-   joinedPath:=filepath.Join(exportPath,binaryName )
-   if _,err= os.Stat(joinedPath);err==nil{
-      tmpExportPath:=fmt.Sprintf("/tmp/%s",uuid.New().String())
-      if mkErr:=os.MkdirAll(tmpExportPath ,0o755);mkErr!=nil{
-          return mkErr
-          }
-
-      if expErr:=dbox.ContainerExportBin(s.InternalName,binary,tmpExportPath,s.IsRootfull );expErr!=nil{
-          return expErr
-          }
-
-      copyErr:=CopyFile(filepath.Join(tmpExportPath,binaryName),filepath.Join(exportPath ,fmt.Sprintf("%s-%s",binaryName,s.InternalName)))
-      if copyErr!=nil{
-          return copyErr
-          }
-
-      removeErr:=os.RemoveAll(tmpExportPath )
-      if removeErr!=nil{
-          return removeErr
-          }
-
-      chmodErr:=os.Chmod(filepath.Join(exportPath,fmt.Sprintf("%s-%s",binaryName,s.InternalName)),0o755 )
-      if chmodErr!=nil{
-          return chmodErr
-          }
-
-      return nil
-   }
-
-   mkDirErr=os.MkdirAll(exportPath ,0o755 )
-   if mkDirErr!=nil{
-       return mkDirErr
-       }
-
-   expBinErr=dbox.ContainerExportBin(s.InternalName,binary ,exportPath,s.IsRootfull )
-   if expBinErr!=nil{
-       return expBinErr
-       }
-
-   return nil
+	if homeDir, homeErr = os.UserHomeDir(); homeErr != nil {
+		return homeErr
+	}
+
+	if exportPath == "" {
+		exportPath = filepath.Join(homeDir, ".local", "bin")
+	}
+
+	joinedPath := filepath.Join(exportPath, binaryName)
+	if IsDryRun() {
+		return backend.ContainerExportBin(s.InternalName, binary, exportPath, s.IsRootfull)
+	}
+
+	if _, err = os.Stat(joinedPath); err == nil {
+		tmpExportPath := fmt.Sprintf("/tmp/%s", uuid.New().String())
+		if mkErr := os.MkdirAll(tmpExportPath, 0o755); mkErr != nil {
+			return mkErr
+		}
+
+		if expErr := backend.ContainerExportBin(s.InternalName, binary, tmpExportPath, s.IsRootfull); expErr != nil {
+			return expErr
+		}
+
+		copyErr := CopyFile(filepath.Join(tmpExportPath, binaryName), filepath.Join(exportPath, fmt.Sprintf("%s-%s", binaryName, s.InternalName)))
+		if copyErr != nil {
+			return copyErr
+		}
+
+		removeErr := os.RemoveAll(tmpExportPath)
+		if removeErr != nil {
+			return removeErr
+		}
+
+		chmodErr := os.Chmod(filepath.Join(exportPath, fmt.Sprintf("%s-%s", binaryName, s.InternalName)), 0o755)
+		if chmodErr != nil {
+			return chmodErr
+		}
+
+		return registerExport(storageName(s.InternalName, s.IsRootfull), &ExportEntry{
+			Type:       ExportTypeBin,
+			Name:       binary,
+			ExportPath: exportPath,
+			HostFiles:  []string{filepath.Join(exportPath, fmt.Sprintf("%s-%s", binaryName, s.InternalName))},
+			ExportedAt: time.Now(),
+		})
+	}
+
+	mkDirErr := os.MkdirAll(exportPath, 0o755)
+	if mkDirErr != nil {
+		return mkDirErr
+	}
+
+	expBinErr := backend.ContainerExportBin(s.InternalName, binary, exportPath, s.IsRootfull)
+	if expBinErr != nil {
+		return expBinErr
+	}
+
+	return registerExport(storageName(s.InternalName, s.IsRootfull), &ExportEntry{
+		Type:       ExportTypeBin,
+		Name:       binary,
+		ExportPath: exportPath,
+		HostFiles:  []string{joinedPath},
+		ExportedAt: time.Now(),
+	})
 }
 
 // UnexportDesktopEntry unexports a desktop entry for an application.
-func (s *SubSystem) UnexportDesktopEntry(appName string)(error){
-	dbox ,err:= NewDbox()
-	if(err!=nil){
-	   return 	err
-	   }
+func (s *SubSystem) UnexportDesktopEntry(appName string) error {
+	backend, err := NewBackend()
+	if err != nil {
+		return err
+	}
+
+	err = backend.ContainerUnexportDesktopEntry(s.InternalName, appName, s.IsRootfull)
+	if err != nil {
+		return err
+	}
 
-	return dbox.ContainerUnexportDesktopEntry(s.InternalName ,appName,s.IsRootfull )
+	return unregisterExport(storageName(s.InternalName, s.IsRootfull), ExportTypeApp, appName)
 }
 
-// UnexportBin unexports a binary from the
+// UnexportBin unexports a binary from the host.
+func (s *SubSystem) UnexportBin(binary string, exportPath string) error {
+	if !strings.HasPrefix(binary, "/") {
+		binaryPath, err := s.Query("which", binary)
+		if err != nil {
+			return err
+		}
+
+		binary = strings.TrimSpace(binaryPath)
+	}
+
+	backend, err := NewBackend()
+	if err != nil {
+		return err
+	}
+
+	err = backend.ContainerUnexportBin(s.InternalName, binary, s.IsRootfull)
+	if err != nil {
+		return err
+	}
+
+	return unregisterExport(storageName(s.InternalName, s.IsRootfull), ExportTypeBin, binary)
+}
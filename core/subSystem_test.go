@@ -0,0 +1,402 @@
+package core
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"sort"
+	"testing"
+)
+
+func TestListSubSystems(t *testing.T) {
+	fake := setupTestAbg(t)
+
+	writeTestFile(t, filepath.Join(abg.Cnf.UserStacksPath, "ubuntu.yaml"), `
+name: ubuntu
+base: docker.io/library/ubuntu:22.04
+pkgmanager: apt
+`)
+
+	fake.Containers[false] = []DBoxContainer{
+		{ID: "1", Status: "Up 1 hour", Name: "abg-dev", Labels: map[string]string{"name": "dev", "stack": "ubuntu"}},
+		{ID: "2", Status: "Exited (0)", Name: "abg-managed", Labels: map[string]string{"name": "managed", "stack": "ubuntu", "managed": "true"}},
+		{ID: "3", Status: "Up 1 hour", Name: "abg-droid", Labels: map[string]string{"name": "droid", "android": "true"}},
+		{ID: "4", Status: "Up 1 hour", Name: "unrelated", Labels: map[string]string{}},
+		{ID: "5", Status: "Up 1 hour", Name: "abg-orphan", Labels: map[string]string{"name": "orphan", "stack": "deleted"}},
+	}
+	fake.Containers[true] = []DBoxContainer{
+		{ID: "6", Status: "Up 1 hour", Name: "abg-root", Labels: map[string]string{"name": "root", "stack": "ubuntu"}},
+	}
+
+	names := func(subSystems []*SubSystem) []string {
+		names := make([]string, 0, len(subSystems))
+		for _, subSystem := range subSystems {
+			names = append(names, subSystem.Name)
+		}
+		sort.Strings(names)
+		return names
+	}
+
+	subSystems, err := ListSubSystems(false, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := names(subSystems); !slices.Equal(got, []string{"dev", "orphan"}) {
+		t.Errorf("ListSubSystems(false, false) = %v, want [dev orphan]", got)
+	}
+	for _, subSystem := range subSystems {
+		if subSystem.Name == "dev" && (subSystem.Stack.Base != "docker.io/library/ubuntu:22.04" || subSystem.Status != "Up 1 hour") {
+			t.Errorf("unexpected subsystem: %+v", subSystem)
+		}
+		if subSystem.Name == "orphan" && subSystem.Stack.Name != "deleted" {
+			t.Errorf("deleted stack should be kept by name: %+v", subSystem.Stack)
+		}
+	}
+
+	subSystems, err = ListSubSystems(true, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := names(subSystems); !slices.Equal(got, []string{"dev", "managed", "orphan"}) {
+		t.Errorf("ListSubSystems(true, false) = %v, want [dev managed orphan]", got)
+	}
+
+	subSystems, err = ListSubSystems(false, true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := names(subSystems); !slices.Equal(got, []string{"dev", "orphan", "root"}) {
+		t.Errorf("ListSubSystems(false, true) = %v, want [dev orphan root]", got)
+	}
+	for _, subSystem := range subSystems {
+		if subSystem.IsRootfull != (subSystem.Name == "root") {
+			t.Errorf("%s: IsRootfull = %t", subSystem.Name, subSystem.IsRootfull)
+		}
+	}
+	for _, call := range fake.CallsTo("list") {
+		if call.RootFull {
+			return
+		}
+	}
+	t.Error("rootful containers were not listed")
+}
+
+func TestCreateWithoutCreationPackages(t *testing.T) {
+	fake := setupTestAbg(t)
+	fake.NoCreatePackages = true
+
+	writeTestPkgManager(t)
+
+	stack := &Stack{Name: "ubuntu", Base: "ubuntu:22.04", PkgManager: "apt", Packages: []string{"htop"}}
+	subSystem := &SubSystem{InternalName: "abg-dev", Name: "dev", Stack: stack}
+
+	err := subSystem.Create()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	creates := fake.CallsTo("create")
+	if len(creates) != 1 || len(creates[0].Args) != 0 {
+		t.Fatalf("packages should not be passed at creation: %+v", creates)
+	}
+	if creates[0].Labels["name"] != "dev" || creates[0].Labels["stack"] != "ubuntu" {
+		t.Errorf("unexpected labels: %v", creates[0].Labels)
+	}
+
+	var commands [][]string
+	for _, call := range fake.CallsTo("exec") {
+		commands = append(commands, call.Args)
+	}
+	want := [][]string{
+		{"sudo", "apt", "update"},
+		{"sudo", "apt", "install", "-y", "htop"},
+	}
+	if !slices.EqualFunc(commands, want, slices.Equal[[]string]) {
+		t.Errorf("exec calls = %v, want %v", commands, want)
+	}
+}
+
+func TestLabelsRoundTrip(t *testing.T) {
+	fake := setupTestAbg(t)
+
+	writeTestFile(t, filepath.Join(abg.Cnf.UserStacksPath, "my stack.yaml"), `
+name: my stack
+base: docker.io/library/ubuntu:22.04
+pkgmanager: apt
+`)
+
+	stack, err := LoadStack("my stack")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	name := `dev box: "α"|β \ 'x'`
+	subSystem := &SubSystem{InternalName: "abg-dev", Name: name, Stack: stack}
+	err = subSystem.Create()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	subSystems, err := ListSubSystems(false, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(subSystems) != 1 || subSystems[0].Name != name || subSystems[0].Stack.Name != "my stack" {
+		t.Errorf("labels did not round-trip: %+v", subSystems)
+	}
+
+	if len(fake.CallsTo("create")) != 1 {
+		t.Errorf("expected one create call, got %+v", fake.CallsTo("create"))
+	}
+}
+
+func TestRecordSurvivesContainer(t *testing.T) {
+	fake := setupTestAbg(t)
+
+	writeTestFile(t, filepath.Join(abg.Cnf.UserStacksPath, "ubuntu.yaml"), `
+name: ubuntu
+base: docker.io/library/ubuntu:22.04
+pkgmanager: apt
+`)
+
+	writeTestPkgManager(t)
+
+	stack, err := LoadStack("ubuntu")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	subSystem := &SubSystem{
+		InternalName:         genInternalName("dev"),
+		Name:                 "dev",
+		Stack:                stack,
+		HasNvidiaIntegration: true,
+		Home:                 "/home/dev",
+		Hostname:             "devbox",
+	}
+	err = subSystem.Create()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	loaded, err := LoadSubSystem("dev", false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !loaded.HasNvidiaIntegration || loaded.Home != "/home/dev" || loaded.Hostname != "devbox" || loaded.CreatedAt.IsZero() {
+		t.Errorf("record was not merged: %+v", loaded)
+	}
+	if loaded.Status != "Created" {
+		t.Errorf("status = %q, want the container status", loaded.Status)
+	}
+
+	err = fake.ContainerDelete(subSystem.InternalName, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	missing, err := LoadSubSystem("dev", false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if missing.Status != SubSystemStatusMissing || missing.Hostname != "devbox" {
+		t.Errorf("unexpected subsystem without container: %+v", missing)
+	}
+
+	err = missing.Reset(false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(fake.CallsTo("create")) != 2 || len(fake.CallsTo("delete")) != 1 {
+		t.Errorf("reset should only recreate the container: %+v", fake.Calls)
+	}
+
+	loaded, err = LoadSubSystem("dev", false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = loaded.Remove()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	_, err = LoadSubSystem("dev", false)
+	if err == nil {
+		t.Error("removed subsystem should not be loaded")
+	}
+}
+
+func TestRootlessAndRootfulStateApart(t *testing.T) {
+	setupTestAbg(t)
+
+	subSystems := map[bool]*SubSystem{}
+	for _, rootFull := range []bool{false, true} {
+		subSystem := &SubSystem{
+			InternalName: genInternalName("dev"),
+			Name:         "dev",
+			Stack:        &Stack{Name: "ubuntu", PkgManager: "apt"},
+			IsRootfull:   rootFull,
+			Hostname:     fmt.Sprintf("dev-%t", rootFull),
+		}
+		subSystems[rootFull] = subSystem
+
+		err := subSystem.saveRecord()
+		if err == nil {
+			err = subSystem.TrackInstalled(fmt.Sprintf("pkg-%t", rootFull))
+		}
+		if err == nil {
+			err = registerExport(storageName(subSystem.InternalName, rootFull), &ExportEntry{Type: ExportTypeApp, Name: fmt.Sprintf("app-%t", rootFull)})
+		}
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	err := subSystems[false].ForgetRecord()
+	if err == nil {
+		err = subSystems[false].ForgetTracking()
+	}
+	if err == nil {
+		_, err = subSystems[false].RemoveExportedFiles()
+	}
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	rootless, err := ListRecordedSubSystems(false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(rootless) != 0 {
+		t.Errorf("the rootless record was not removed: %+v", rootless)
+	}
+
+	rootful, err := ListRecordedSubSystems(true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(rootful) != 1 || rootful[0].Hostname != "dev-true" {
+		t.Fatalf("the rootful record was changed: %+v", rootful)
+	}
+
+	tracking, err := rootful[0].LoadTracking()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !slices.Equal(tracking.Installed, []string{"pkg-true"}) {
+		t.Errorf("rootful tracking = %v, want [pkg-true]", tracking.Installed)
+	}
+
+	exports, err := rootful[0].LoadExports()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(exports) != 1 || exports[0].Name != "app-true" {
+		t.Errorf("unexpected rootful exports: %+v", exports)
+	}
+}
+
+func TestResetKeepsCreationOptions(t *testing.T) {
+	fake := setupTestAbg(t)
+
+	writeTestPkgManager(t)
+
+	stack := &Stack{Name: "ubuntu", Base: "ubuntu:22.04", PkgManager: "apt"}
+	subSystem := &SubSystem{
+		InternalName: genInternalName("dev"),
+		Name:         "dev",
+		Stack:        stack,
+		IsUnshared:   true,
+		Hostname:     "devbox",
+		Mounts:       []Mount{{Source: "/srv/data", Target: "/data", ReadOnly: true}},
+		Env:          []string{"EDITOR=vim"},
+		EngineFlags:  []string{"--cap-add=SYS_PTRACE"},
+	}
+	err := subSystem.Create()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	loaded, err := LoadSubSystem("dev", false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = loaded.Reset(false)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	creates := fake.CallsTo("create")
+	if len(creates) != 2 {
+		t.Fatalf("expected two create calls, got %d", len(creates))
+	}
+	first, second := creates[0].Extras, creates[1].Extras
+	if !slices.Equal(first.Volumes, second.Volumes) || !slices.Equal(first.Env, second.Env) || !slices.Equal(first.EngineFlags, second.EngineFlags) {
+		t.Errorf("reset changed the container options: %+v -> %+v", first, second)
+	}
+	if !slices.Equal(second.Volumes, []string{"/srv/data:/data:ro"}) {
+		t.Errorf("unexpected volumes: %v", second.Volumes)
+	}
+	if !loaded.IsUnshared || loaded.Hostname != "devbox" {
+		t.Errorf("creation options were not persisted: %+v", loaded)
+	}
+
+	invalid := &SubSystem{InternalName: genInternalName("bad"), Name: "bad", Stack: stack, Env: []string{"NOVALUE"}}
+	if err := invalid.Create(); err == nil {
+		t.Error("invalid environment variables should be rejected")
+	}
+}
+
+func TestResetFailureKeepsExportsAndTracking(t *testing.T) {
+	fake := setupTestAbg(t)
+	writeTestPkgManager(t)
+
+	stack := &Stack{Name: "ubuntu", Base: "ubuntu:22.04", PkgManager: "apt"}
+	subSystem := &SubSystem{InternalName: genInternalName("dev"), Name: "dev", Stack: stack}
+	err := subSystem.Create()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = subSystem.TrackInstalled("ripgrep")
+	if err != nil {
+		t.Fatal(err)
+	}
+	launcher := filepath.Join(t.TempDir(), "rg")
+	writeTestFile(t, launcher, "#!/bin/sh\n# abg_binary\n")
+	err = registerExport(subSystem.InternalName, &ExportEntry{Type: ExportTypeBin, Name: "/usr/bin/rg", ExportPath: filepath.Dir(launcher), HostFiles: []string{launcher}})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	assertKept := func(step string) {
+		t.Helper()
+
+		if _, err := os.Stat(launcher); err != nil {
+			t.Errorf("%s: exported launcher was removed", step)
+		}
+		exports, err := subSystem.LoadExports()
+		if err != nil || len(exports) != 1 {
+			t.Errorf("%s: exports were forgotten: %v", step, exports)
+		}
+		tracking, err := subSystem.LoadTracking()
+		if err != nil || !slices.Equal(tracking.Installed, []string{"ripgrep"}) {
+			t.Errorf("%s: tracking was lost: %+v", step, tracking)
+		}
+	}
+
+	fake.Errors["create "+subSystem.InternalName] = errors.New("image not found")
+	if err := subSystem.Reset(false); err == nil {
+		t.Fatal("reset should fail when the container can't be created")
+	}
+	assertKept("failed creation")
+
+	delete(fake.Errors, "create "+subSystem.InternalName)
+	fake.Errors["sudo apt install -y ripgrep"] = errors.New("exit status 100")
+	if err := subSystem.Reset(false); err == nil {
+		t.Fatal("reset should fail when the tracked packages can't be installed")
+	}
+	assertKept("failed restore")
+}
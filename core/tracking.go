@@ -0,0 +1,149 @@
+package core
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+)
+
+// Tracking stores what the user changed in a subsystem after its creation,
+// so it can be restored when the subsystem is recreated.
+type Tracking struct {
+	Installed []string // Packages installed through the runtime install command
+	Removed   []string // Packages removed through the runtime remove and purge commands
+}
+
+// trackingPath returns the path of the tracking file of a subsystem.
+func trackingPath(internalName string, isRootFull bool) string {
+	return filepath.Join(abg.Cnf.AbgStoragePath, "tracking", storageName(internalName, isRootFull)+".json")
+}
+
+// LoadTracking loads the tracking of a subsystem, an empty one is returned
+// if nothing was tracked yet.
+func (s *SubSystem) LoadTracking() (*Tracking, error) {
+	tracking := &Tracking{}
+
+	data, err := os.ReadFile(trackingPath(s.InternalName, s.IsRootfull))
+	if err != nil {
+		if os.IsNotExist(err) {
+			return tracking, nil
+		}
+		return nil, err
+	}
+
+	err = json.Unmarshal(data, tracking)
+	if err != nil {
+		return nil, err
+	}
+
+	return tracking, nil
+}
+
+// saveTracking writes the tracking of a subsystem.
+func (s *SubSystem) saveTracking(tracking *Tracking) error {
+	if IsDryRun() {
+		return nil
+	}
+
+	data, err := json.MarshalIndent(tracking, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	path := trackingPath(s.InternalName, s.IsRootfull)
+	err = os.MkdirAll(filepath.Dir(path), 0755)
+	if err != nil {
+		return err
+	}
+
+	return os.WriteFile(path, data, 0644)
+}
+
+// updateTracking loads, changes and saves the tracking of a subsystem.
+func (s *SubSystem) updateTracking(update func(*Tracking)) error {
+	tracking, err := s.LoadTracking()
+	if err != nil {
+		return err
+	}
+
+	update(tracking)
+
+	return s.saveTracking(tracking)
+}
+
+// ForgetTracking deletes the tracking of a subsystem.
+func (s *SubSystem) ForgetTracking() error {
+	if IsDryRun() {
+		return nil
+	}
+
+	err := os.Remove(trackingPath(s.InternalName, s.IsRootfull))
+	if err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	return nil
+}
+
+// TrackInstalled records packages installed by the user. Arguments starting
+// with a dash are package manager flags and are ignored.
+func (s *SubSystem) TrackInstalled(packages ...string) error {
+	return s.updateTracking(func(t *Tracking) {
+		for _, pkg := range packages {
+			if strings.HasPrefix(pkg, "-") {
+				continue
+			}
+
+			t.Removed = slices.DeleteFunc(t.Removed, func(p string) bool { return p == pkg })
+			if !slices.Contains(t.Installed, pkg) {
+				t.Installed = append(t.Installed, pkg)
+			}
+		}
+	})
+}
+
+// TrackRemoved records packages removed by the user. Only packages coming
+// from the stack need to be removed again on restore.
+func (s *SubSystem) TrackRemoved(packages ...string) error {
+	return s.updateTracking(func(t *Tracking) {
+		for _, pkg := range packages {
+			if strings.HasPrefix(pkg, "-") {
+				continue
+			}
+
+			t.Installed = slices.DeleteFunc(t.Installed, func(p string) bool { return p == pkg })
+			if slices.Contains(s.Stack.Packages, pkg) && !slices.Contains(t.Removed, pkg) {
+				t.Removed = append(t.Removed, pkg)
+			}
+		}
+	})
+}
+
+// restoreTracking replays the tracked changes on a freshly created
+// subsystem: user packages are installed and removed stack packages are
+// removed again. The tracking is left as it is, so it still applies when
+// the restore fails.
+func (s *SubSystem) restoreTracking(tracking *Tracking) error {
+	pkgManager, err := s.Stack.GetPkgManager()
+	if err != nil {
+		return err
+	}
+
+	if len(tracking.Installed) > 0 {
+		_, err := s.Exec(false, false, pkgManager.GenCmd(pkgManager.CmdInstall, tracking.Installed...)...)
+		if err != nil {
+			return fmt.Errorf("failed to restore installed packages: %w", err)
+		}
+	}
+
+	if len(tracking.Removed) > 0 {
+		_, err := s.Exec(false, false, pkgManager.GenCmd(pkgManager.CmdRemove, tracking.Removed...)...)
+		if err != nil {
+			return fmt.Errorf("failed to remove packages again: %w", err)
+		}
+	}
+
+	return nil
+}
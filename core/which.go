@@ -0,0 +1,168 @@
+package core
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+)
+
+// WhichResult is a subsystem providing a command or package looked up by
+// Which.
+type WhichResult struct {
+	SubSystem     string   `json:"subsystem"`
+	Stack         string   `json:"stack"`
+	Path          string   `json:"path,omitempty"` // Path of the command inside the subsystem
+	Package       string   `json:"package,omitempty"`
+	Version       string   `json:"version,omitempty"`
+	HostFiles     []string `json:"hostFiles,omitempty"` // Files exported on the host
+	CollisionCopy bool     `json:"collisionCopy"`       // The host copy is the <bin>-<internalName> variant
+}
+
+// Which looks for the subsystems providing a command or package. The export
+// registry tells which subsystem the host files come from, while the
+// subsystems are queried with which and the package manager list command,
+// at most DefaultMaintenanceJobs at the same time. The package of a command is the one owning its path, as told by
+// the package database, else the package with the given name.
+func Which(name string) ([]*WhichResult, error) {
+	subSystems, err := ListSubSystems(false, false)
+	if err != nil {
+		return nil, err
+	}
+
+	results := make([]*WhichResult, len(subSystems))
+	slots := make(chan struct{}, DefaultMaintenanceJobs)
+
+	var wg sync.WaitGroup
+	for i, subSystem := range subSystems {
+		wg.Add(1)
+		go func(i int, subSystem *SubSystem) {
+			defer wg.Done()
+
+			slots <- struct{}{}
+			results[i] = subSystem.which(name)
+			<-slots
+		}(i, subSystem)
+	}
+	wg.Wait()
+
+	found := make([]*WhichResult, 0)
+	for _, result := range results {
+		if result != nil {
+			found = append(found, result)
+		}
+	}
+
+	return found, nil
+}
+
+// which looks for a command or package in the subsystem, returning nil if
+// the subsystem doesn't provide it.
+func (s *SubSystem) which(name string) *WhichResult {
+	result := &WhichResult{
+		SubSystem: s.Name,
+		Stack:     s.Stack.Name,
+	}
+
+	// The <bin>-<internalName> copy shown on the host names the binary
+	name = strings.TrimSuffix(name, "-"+s.InternalName)
+
+	for _, entry := range s.Exports {
+		if entry.Type != ExportTypeBin || filepath.Base(entry.Name) != name {
+			continue
+		}
+
+		result.HostFiles = append(result.HostFiles, entry.HostFiles...)
+		for _, file := range entry.HostFiles {
+			if filepath.Base(file) == name+"-"+s.InternalName {
+				result.CollisionCopy = true
+			}
+		}
+	}
+
+	// Copies made before the export registry existed are only found on disk
+	if !result.CollisionCopy {
+		if binDir, err := hostBinDir(); err == nil {
+			copyPath := filepath.Join(binDir, name+"-"+s.InternalName)
+			if _, err := os.Stat(copyPath); err == nil {
+				result.HostFiles = append(result.HostFiles, copyPath)
+				result.CollisionCopy = true
+			}
+		}
+	}
+
+	out, err := s.Query("which", name)
+	if err == nil {
+		result.Path = strings.TrimSpace(out)
+	}
+
+	pkgName := name
+	if result.Path != "" {
+		if owner := s.packageOwning(result.Path); owner != "" {
+			pkgName = owner
+		}
+	}
+
+	packages, err := s.InstalledPackages()
+	if err == nil {
+		for _, pkg := range packages {
+			if pkg.Name == pkgName {
+				result.Package = pkg.Name
+				result.Version = pkg.Version
+				break
+			}
+		}
+	}
+
+	if result.Path == "" && result.Package == "" && len(result.HostFiles) == 0 {
+		return nil
+	}
+
+	return result
+}
+
+// packageOwning returns the package owning a path in the subsystem, queried
+// from the package database of well-known package managers. An empty name
+// is returned if it is unknown.
+func (s *SubSystem) packageOwning(path string) string {
+	var args []string
+	switch s.Stack.PkgManager {
+	case "apt", "apt-get", "nala":
+		args = []string{"dpkg", "-S", path}
+	case "dnf", "yum", "microdnf", "zypper":
+		args = []string{"rpm", "-qf", "--queryformat", "%{NAME}\\n", path}
+	case "pacman", "yay", "paru":
+		args = []string{"pacman", "-Qoq", path}
+	default:
+		return ""
+	}
+
+	out, err := s.Query(args...)
+	if err != nil {
+		return ""
+	}
+
+	return parsePackageOwner(out)
+}
+
+// parsePackageOwner parses the owner query output, either a package name,
+// or a "package: path" line as dpkg -S prints.
+func parsePackageOwner(out string) string {
+	for _, line := range strings.Split(out, "\n") {
+		line = strings.TrimSpace(line)
+		if line == "" || strings.HasPrefix(line, "diversion by") {
+			continue
+		}
+
+		// dpkg lists every package sharing the path
+		if owners, _, ok := strings.Cut(line, ": "); ok {
+			line = owners
+		}
+		owner, _, _ := strings.Cut(line, ", ")
+		owner, _, _ = strings.Cut(owner, ":") // Architecture qualifier
+
+		return strings.TrimSpace(owner)
+	}
+
+	return ""
+}
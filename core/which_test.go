@@ -0,0 +1,82 @@
+package core
+
+import (
+	"path/filepath"
+	"testing"
+)
+
+func TestWhichResolvesOwningPackage(t *testing.T) {
+	fake := setupTestAbg(t)
+	writeTestPkgManager(t)
+
+	writeTestFile(t, filepath.Join(abg.Cnf.UserStacksPath, "ubuntu.yaml"), `
+name: ubuntu
+base: docker.io/library/ubuntu:22.04
+pkgmanager: apt
+`)
+	fake.Containers[false] = []DBoxContainer{
+		{ID: "1", Status: "Up 1 hour", Name: "abg-dev", Labels: map[string]string{"name": "dev", "stack": "ubuntu"}},
+	}
+	fake.Outputs["which rg"] = "/usr/bin/rg\n"
+	fake.Outputs["dpkg -S /usr/bin/rg"] = "ripgrep: /usr/bin/rg\n"
+	fake.Outputs["sudo apt list --installed"] = "ripgrep/jammy,now 13.0.0-2ubuntu0.1 amd64 [installed]\n"
+
+	for _, name := range []string{"rg", "rg-abg-dev"} {
+		results, err := Which(name)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(results) != 1 {
+			t.Fatalf("Which(%q) found %d subsystems, want 1", name, len(results))
+		}
+
+		result := results[0]
+		if result.Path != "/usr/bin/rg" || result.Package != "ripgrep" || result.Version != "13.0.0-2ubuntu0.1" {
+			t.Errorf("Which(%q) = %+v", name, result)
+		}
+	}
+}
+
+func TestParsePackageOwner(t *testing.T) {
+	tests := map[string]string{
+		"ripgrep\n":                    "ripgrep",
+		"ripgrep: /usr/bin/rg\n":       "ripgrep",
+		"libc6:amd64: /usr/bin/ldd\n":  "libc6",
+		"vim, vim-tiny: /usr/bin/vi\n": "vim",
+		"diversion by dash from: /bin/sh\ndiversion by dash to: /bin/sh.distrib\ndash: /bin/sh\n": "dash",
+		"": "",
+	}
+
+	for out, want := range tests {
+		if got := parsePackageOwner(out); got != want {
+			t.Errorf("parsePackageOwner(%q) = %q, want %q", out, got, want)
+		}
+	}
+}
+
+func TestWhichBoundsConcurrentQueries(t *testing.T) {
+	fake := setupTestAbg(t)
+	writeTestPkgManager(t)
+	writeTestFile(t, filepath.Join(abg.Cnf.UserStacksPath, "ubuntu.yaml"), `
+name: ubuntu
+base: docker.io/library/ubuntu:22.04
+pkgmanager: apt
+`)
+	addTestContainers(fake, "one", "two", "three", "four", "five", "six")
+
+	probe := probeConcurrency(fake, func(call FakeCall) bool {
+		return call.Method == "query" && len(call.Args) > 0 && call.Args[0] == "which"
+	})
+
+	_, err := Which("rg")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(fake.CallsTo("query")) == 0 {
+		t.Fatal("no subsystem was queried")
+	}
+	if probe.max() > DefaultMaintenanceJobs {
+		t.Errorf("%d subsystems queried at the same time, want at most %d", probe.max(), DefaultMaintenanceJobs)
+	}
+}
@@ -0,0 +1,383 @@
+package core
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+
+	"gopkg.in/yaml.v2"
+)
+
+// Workspace represents a declarative set of subsystems abg converges the
+// machine to.
+type Workspace struct {
+	SubSystems []WorkspaceSubSystem `yaml:"subsystems"`
+	Prune      bool                 `yaml:"prune"` // If true, subsystems not listed in the workspace are removed
+}
+
+// WorkspaceSubSystem describes a subsystem in a workspace file.
+type WorkspaceSubSystem struct {
+	Name     string   `yaml:"name"`
+	Stack    string   `yaml:"stack"`
+	Home     string   `yaml:"home"`
+	Init     bool     `yaml:"init"`
+	Nvidia   bool     `yaml:"nvidia"`
+	Unshared bool     `yaml:"unshared"`
+	Hostname string   `yaml:"hostname"`
+	Packages []string `yaml:"packages"`
+	Apps     []string `yaml:"apps"`
+	Bins     []string `yaml:"bins"`
+}
+
+// Workspace actions reported by Plan.
+const (
+	WorkspaceActionCreate = "create"
+	WorkspaceActionDrift  = "drift"  // Creation options differ, the subsystem is recreated
+	WorkspaceActionUpdate = "update" // Declared packages, apps or bins are missing
+	WorkspaceActionRemove = "remove"
+	WorkspaceActionNone   = "none"
+)
+
+// WorkspaceChange is a single step needed to converge to the workspace.
+type WorkspaceChange struct {
+	Action    string
+	Name      string
+	Stack     string
+	Details   []string
+	Entry     *WorkspaceSubSystem
+	SubSystem *SubSystem
+}
+
+// LoadWorkspace loads a workspace from the specified path.
+func LoadWorkspace(path string) (*Workspace, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, errors.New("workspace file not found")
+	}
+	defer f.Close()
+
+	data, err := io.ReadAll(f)
+	if err != nil {
+		return nil, err
+	}
+
+	workspace := &Workspace{}
+	err = yaml.Unmarshal(data, workspace)
+	if err != nil {
+		return nil, err
+	}
+
+	seen := map[string]bool{}
+	for _, entry := range workspace.SubSystems {
+		if entry.Name == "" || entry.Stack == "" {
+			return nil, errors.New("invalid workspace file: every subsystem needs a name and a stack")
+		}
+		if seen[entry.Name] {
+			return nil, fmt.Errorf("invalid workspace file: subsystem %s is declared twice", entry.Name)
+		}
+		seen[entry.Name] = true
+
+		if !StackExists(entry.Stack) {
+			return nil, fmt.Errorf("invalid workspace file: stack %s does not exist", entry.Stack)
+		}
+	}
+
+	return workspace, nil
+}
+
+// Plan compares the workspace with the existing subsystems and returns the
+// changes needed to converge to it.
+func (w *Workspace) Plan() ([]*WorkspaceChange, error) {
+	existing, err := ListSubSystems(false, false)
+	if err != nil {
+		return nil, err
+	}
+
+	existingByName := map[string]*SubSystem{}
+	for _, subSystem := range existing {
+		existingByName[subSystem.Name] = subSystem
+	}
+
+	changes := make([]*WorkspaceChange, 0)
+	for i := range w.SubSystems {
+		entry := &w.SubSystems[i]
+
+		if _, ok := existingByName[entry.Name]; !ok {
+			changes = append(changes, &WorkspaceChange{
+				Action: WorkspaceActionCreate,
+				Name:   entry.Name,
+				Stack:  entry.Stack,
+				Entry:  entry,
+			})
+			continue
+		}
+
+		subSystem, err := LoadSubSystem(entry.Name, false)
+		if err != nil {
+			return nil, err
+		}
+
+		missing, err := entry.missing(subSystem)
+		if err != nil {
+			return nil, err
+		}
+
+		details := entry.drift(subSystem)
+		action := WorkspaceActionNone
+		switch {
+		case len(details) > 0:
+			action = WorkspaceActionDrift
+		case !missing.empty():
+			action = WorkspaceActionUpdate
+		}
+		details = append(details, missing.details()...)
+
+		changes = append(changes, &WorkspaceChange{
+			Action:    action,
+			Name:      entry.Name,
+			Stack:     entry.Stack,
+			Details:   details,
+			Entry:     entry,
+			SubSystem: subSystem,
+		})
+	}
+
+	if w.Prune {
+		declared := map[string]bool{}
+		for _, entry := range w.SubSystems {
+			declared[entry.Name] = true
+		}
+
+		for _, subSystem := range existing {
+			if declared[subSystem.Name] {
+				continue
+			}
+
+			changes = append(changes, &WorkspaceChange{
+				Action:    WorkspaceActionRemove,
+				Name:      subSystem.Name,
+				Stack:     subSystem.Stack.Name,
+				SubSystem: subSystem,
+			})
+		}
+	}
+
+	return changes, nil
+}
+
+// drift returns the creation options of an existing subsystem differing
+// from the workspace entry. They can't be changed on an existing container,
+// so the subsystem has to be recreated.
+func (e *WorkspaceSubSystem) drift(subSystem *SubSystem) []string {
+	details := make([]string, 0)
+
+	if subSystem.Stack.Name != e.Stack {
+		details = append(details, fmt.Sprintf("stack: %s -> %s", subSystem.Stack.Name, e.Stack))
+	}
+	if subSystem.HasInit != e.Init {
+		details = append(details, fmt.Sprintf("init: %t -> %t", subSystem.HasInit, e.Init))
+	}
+	if subSystem.IsUnshared != e.Unshared {
+		details = append(details, fmt.Sprintf("unshared: %t -> %t", subSystem.IsUnshared, e.Unshared))
+	}
+	if subSystem.HasNvidiaIntegration != e.Nvidia {
+		details = append(details, fmt.Sprintf("nvidia: %t -> %t", subSystem.HasNvidiaIntegration, e.Nvidia))
+	}
+	if subSystem.Hostname != e.Hostname {
+		details = append(details, fmt.Sprintf("hostname: %q -> %q", subSystem.Hostname, e.Hostname))
+	}
+	if subSystem.Home != e.Home {
+		details = append(details, fmt.Sprintf("home: %q -> %q", subSystem.Home, e.Home))
+	}
+
+	return details
+}
+
+// workspaceMissing is what a workspace entry declares and its subsystem
+// lacks.
+type workspaceMissing struct {
+	Packages []string
+	Apps     []string
+	Bins     []string
+}
+
+func (m *workspaceMissing) empty() bool {
+	return len(m.Packages) == 0 && len(m.Apps) == 0 && len(m.Bins) == 0
+}
+
+func (m *workspaceMissing) details() []string {
+	details := make([]string, 0)
+	if len(m.Packages) > 0 {
+		details = append(details, "install: "+strings.Join(m.Packages, ", "))
+	}
+	if len(m.Apps) > 0 {
+		details = append(details, "export apps: "+strings.Join(m.Apps, ", "))
+	}
+	if len(m.Bins) > 0 {
+		details = append(details, "export bins: "+strings.Join(m.Bins, ", "))
+	}
+
+	return details
+}
+
+// missing returns the declared packages, apps and bins the subsystem lacks.
+// Packages are checked against the package manager list, or against the
+// stack and tracked packages if it can't be listed.
+func (e *WorkspaceSubSystem) missing(subSystem *SubSystem) (*workspaceMissing, error) {
+	missing := &workspaceMissing{}
+
+	if len(e.Packages) > 0 {
+		installed := map[string]bool{}
+		packages, err := subSystem.InstalledPackages()
+		if err == nil {
+			for _, pkg := range packages {
+				installed[pkg.Name] = true
+			}
+		} else {
+			tracking, err := subSystem.LoadTracking()
+			if err != nil {
+				return nil, err
+			}
+			for _, pkg := range append(slices.Clone(subSystem.Stack.Packages), tracking.Installed...) {
+				installed[pkg] = true
+			}
+		}
+
+		for _, pkg := range e.Packages {
+			if !installed[pkg] {
+				missing.Packages = append(missing.Packages, pkg)
+			}
+		}
+	}
+
+	exports, err := subSystem.LoadExports()
+	if err != nil {
+		return nil, err
+	}
+
+	for _, app := range e.Apps {
+		if !slices.ContainsFunc(exports, func(entry *ExportEntry) bool {
+			return entry.Type == ExportTypeApp && entry.Name == app
+		}) {
+			missing.Apps = append(missing.Apps, app)
+		}
+	}
+	for _, bin := range e.Bins {
+		if !slices.ContainsFunc(exports, func(entry *ExportEntry) bool {
+			return entry.Type == ExportTypeBin && (entry.Name == bin || filepath.Base(entry.Name) == filepath.Base(bin))
+		}) {
+			missing.Bins = append(missing.Bins, bin)
+		}
+	}
+
+	return missing, nil
+}
+
+// Apply executes a change returned by Plan.
+func (c *WorkspaceChange) Apply() error {
+	switch c.Action {
+	case WorkspaceActionCreate:
+		return c.Entry.create()
+	case WorkspaceActionDrift:
+		return c.Entry.recreate(c.SubSystem)
+	case WorkspaceActionUpdate:
+		return c.Entry.converge(c.SubSystem)
+	case WorkspaceActionRemove:
+		_, err := c.SubSystem.Remove()
+		return err
+	}
+
+	return nil
+}
+
+// create creates the subsystem described by the workspace entry, installs
+// its packages and exports its apps and binaries.
+func (e *WorkspaceSubSystem) create() error {
+	stack, err := LoadStack(e.Stack)
+	if err != nil {
+		return err
+	}
+
+	subSystem, err := NewSubSystem(e.Name, stack, e.Home, e.Init, false, false, e.Unshared, e.Nvidia, e.Hostname)
+	if err != nil {
+		return err
+	}
+
+	err = subSystem.Create()
+	if err != nil {
+		return err
+	}
+
+	return e.converge(subSystem)
+}
+
+// recreate resets the subsystem with the creation options of the workspace
+// entry. Its tracked packages and exports are restored, then what the entry
+// declares is added.
+func (e *WorkspaceSubSystem) recreate(subSystem *SubSystem) error {
+	stack, err := LoadStack(e.Stack)
+	if err != nil {
+		return err
+	}
+
+	subSystem.Stack = stack
+	subSystem.HasInit = e.Init
+	subSystem.IsUnshared = e.Unshared
+	subSystem.HasNvidiaIntegration = e.Nvidia
+	subSystem.Hostname = e.Hostname
+	subSystem.Home = e.Home
+
+	err = subSystem.Reset(false)
+	if err != nil {
+		return err
+	}
+
+	return e.converge(subSystem)
+}
+
+// converge installs the declared packages and exports the declared apps and
+// binaries the subsystem lacks. Installed packages are tracked, so a reset
+// restores them.
+func (e *WorkspaceSubSystem) converge(subSystem *SubSystem) error {
+	missing, err := e.missing(subSystem)
+	if err != nil {
+		return err
+	}
+
+	if len(missing.Packages) > 0 {
+		pkgManager, err := subSystem.Stack.GetPkgManager()
+		if err != nil {
+			return err
+		}
+
+		_, err = subSystem.Exec(false, false, pkgManager.GenCmd(pkgManager.CmdInstall, missing.Packages...)...)
+		if err != nil {
+			return fmt.Errorf("failed to install packages: %w", err)
+		}
+
+		err = subSystem.TrackInstalled(missing.Packages...)
+		if err != nil {
+			return err
+		}
+	}
+
+	for _, app := range missing.Apps {
+		err := subSystem.ExportDesktopEntry(app)
+		if err != nil {
+			return fmt.Errorf("failed to export %s: %w", app, err)
+		}
+	}
+
+	for _, bin := range missing.Bins {
+		err := subSystem.ExportBin(bin, "")
+		if err != nil {
+			return fmt.Errorf("failed to export %s: %w", bin, err)
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,89 @@
+package core
+
+import (
+	"path/filepath"
+	"slices"
+	"testing"
+)
+
+func TestWorkspaceConvergesExistingSubSystems(t *testing.T) {
+	fake := setupTestAbg(t)
+	writeTestPkgManager(t)
+
+	writeTestFile(t, filepath.Join(abg.Cnf.UserStacksPath, "ubuntu.yaml"), `
+name: ubuntu
+base: docker.io/library/ubuntu:22.04
+pkgmanager: apt
+packages: [git]
+`)
+	stack, err := LoadStack("ubuntu")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, name := range []string{"dev", "gpu"} {
+		subSystem := &SubSystem{InternalName: genInternalName(name), Name: name, Stack: stack}
+		err = subSystem.Create()
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	fake.Outputs["sudo apt list --installed"] = "git/jammy,now 1:2.34.1-1ubuntu1 amd64 [installed]\n"
+
+	workspace := &Workspace{SubSystems: []WorkspaceSubSystem{
+		{Name: "dev", Stack: "ubuntu", Packages: []string{"git", "ripgrep"}},
+		{Name: "gpu", Stack: "ubuntu", Nvidia: true, Hostname: "gpubox"},
+	}}
+
+	changes, err := workspace.Plan()
+	if err != nil {
+		t.Fatal(err)
+	}
+	actions := map[string]*WorkspaceChange{}
+	for _, change := range changes {
+		actions[change.Name] = change
+	}
+	if actions["dev"].Action != WorkspaceActionUpdate || !slices.Equal(actions["dev"].Details, []string{"install: ripgrep"}) {
+		t.Errorf("missing packages should be installed in place: %+v", actions["dev"])
+	}
+	if actions["gpu"].Action != WorkspaceActionDrift || len(actions["gpu"].Details) != 2 {
+		t.Errorf("creation options should recreate the subsystem: %+v", actions["gpu"])
+	}
+
+	for _, change := range changes {
+		err = change.Apply()
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	installs := 0
+	for _, call := range fake.CallsTo("exec") {
+		if slices.Equal(call.Args, []string{"sudo", "apt", "install", "-y", "ripgrep"}) {
+			installs++
+		}
+	}
+	if installs != 1 {
+		t.Errorf("ripgrep installed %d times, want 1", installs)
+	}
+
+	dev, err := LoadSubSystem("dev", false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tracking, err := dev.LoadTracking()
+	if err != nil || !slices.Equal(tracking.Installed, []string{"ripgrep"}) {
+		t.Errorf("workspace packages should be tracked: %+v", tracking)
+	}
+
+	gpu, err := LoadSubSystem("gpu", false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !gpu.HasNvidiaIntegration || gpu.Hostname != "gpubox" {
+		t.Errorf("subsystem was not recreated with the declared options: %+v", gpu)
+	}
+	if creates := fake.CallsTo("create"); len(creates) != 3 {
+		t.Errorf("expected one recreation, got %d creations", len(creates))
+	}
+}
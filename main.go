@@ -66,6 +66,24 @@ func registerCommands(root *cmdr.Command) {
 	pkgManagers := cmd.NewPkgManagersCommand()
 	root.AddCommand(pkgManagers)
 
+	android := cmd.NewAndroidCommand()
+	root.AddCommand(android)
+
+	build := cmd.NewBuildCommand()
+	root.AddCommand(build)
+
+	apply := cmd.NewApplyCommand()
+	root.AddCommand(apply)
+
+	search := cmd.NewSearchCommand()
+	root.AddCommand(search)
+
+	which := cmd.NewWhichCommand()
+	root.AddCommand(which)
+
+	maintenanceCmds := cmd.NewMaintenanceCommands()
+	root.AddCommand(maintenanceCmds...)
+
 	runtimeCmds := cmd.NewRuntimeCommands()
 	root.AddCommand(runtimeCmds...)
 }
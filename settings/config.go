@@ -14,6 +14,7 @@ type Config struct {
 	AbgPath       string `json:"abgPath"`
 	DistroboxPath string `json:"distroboxPath"`
 	StorageDriver string `json:"storageDriver"`
+	Backend       string `json:"backend"` // distrobox (default) or podman
 
 	// Virtual
 	UserAbgPath         string
@@ -22,6 +23,8 @@ type Config struct {
 	UserStacksPath      string
 	PkgManagersPath     string
 	UserPkgManagersPath string
+	RecipesPath         string
+	UserRecipesPath     string
 }
 
 func GetAbgDefaultConfig() (*Config, error) {
@@ -71,6 +74,7 @@ func GetAbgDefaultConfig() (*Config, error) {
 		distroboxPath,
 		viper.GetString("storageDriver"),
 	)
+	Cnf.Backend = viper.GetString("backend")
 	return Cnf, nil
 }
 
@@ -93,6 +97,8 @@ func NewAbgConfig(abgPath, distroboxPath, storageDriver string) *Config {
 		UserStacksPath:      "",
 		PkgManagersPath:     "",
 		UserPkgManagersPath: "",
+		RecipesPath:         "",
+		UserRecipesPath:     "",
 	}
 
 	Cnf.UserAbgPath = filepath.Join(userHome, ".local/share/abg")
@@ -101,6 +107,8 @@ func NewAbgConfig(abgPath, distroboxPath, storageDriver string) *Config {
 	Cnf.UserStacksPath = filepath.Join(Cnf.UserAbgPath, "stacks")
 	Cnf.PkgManagersPath = filepath.Join(Cnf.AbgPath, "package-managers")
 	Cnf.UserPkgManagersPath = filepath.Join(Cnf.UserAbgPath, "package-managers")
+	Cnf.RecipesPath = filepath.Join(Cnf.AbgPath, "recipes")
+	Cnf.UserRecipesPath = filepath.Join(Cnf.UserAbgPath, "recipes")
 
 	return Cnf
 }